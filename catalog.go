@@ -8,6 +8,7 @@ type catalog struct {
 	pages    *pages
 	streams  []obj
 	acroform *acroform
+	outlines *outlines
 
 	images []*Image
 	xforms []*XContent
@@ -41,6 +42,9 @@ func (c *catalog) children() []obj {
 		out[i] = c.streams[j]
 		i++
 	}
+	if c.outlines != nil && len(c.outlines.items) > 0 {
+		out = append(out, c.outlines)
+	}
 	return out
 }
 func (c *catalog) mark(i int) { c.refnum = i }
@@ -59,6 +63,9 @@ func (c *catalog) encode(w io.Writer) (int, error) {
 			"/AcroForm", iref(c.acroform),
 		})
 	}
+	if c.outlines != nil && len(c.outlines.items) > 0 {
+		fields = append(fields, field{"/Outlines", iref(c.outlines)})
+	}
 	if b := c.ViewPrefs.bytes(); b != nil {
 		fields = append(fields, field{"/ViewerPreferences", b})
 	}
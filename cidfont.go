@@ -0,0 +1,135 @@
+package gdf
+
+import (
+	"io"
+	"slices"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// A cidFont is the CIDFontType2 descendant of a composite Font. Since the parent Font uses the Identity-H encoding and an
+// Identity CIDToGIDMap, each 2-byte character code is equal to the ID of the glyph that it selects.
+type cidFont struct {
+	parent *Font
+	w      []byte // the W (glyph widths) array
+	refnum int
+}
+
+func (c *cidFont) mark(i int)      { c.refnum = i }
+func (c *cidFont) id() int         { return c.refnum }
+func (c *cidFont) children() []obj { return nil } // the font descriptor is included by the parent Font
+func (c *cidFont) encode(w io.Writer) (int, error) {
+	return w.Write(dict(512+len(c.w), []field{
+		{"/Type", "/Font"},
+		{"/Subtype", "/CIDFontType2"},
+		{"/BaseFont", c.parent.baseFont},
+		{"/CIDSystemInfo", "<< /Registry (Adobe) /Ordering (Identity) /Supplement 0 >>"},
+		{"/FontDescriptor", iref(c.parent.simpleFD)},
+		{"/W", c.w},
+		{"/CIDToGIDMap", "/Identity"},
+	}))
+}
+
+// glyphIDAdvance returns the advance of the glyph with the given ID in font units.
+func (f *Font) glyphIDAdvance(gid uint16) int {
+	adv26_6, err := f.SFNT.GlyphAdvance(f.buf, sfnt.GlyphIndex(gid), 1000, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return int(adv26_6)
+}
+
+// useGlyph records that the glyph gid, which represents the text t, has been drawn with the composite font f, and returns its
+// 2-byte character code.
+func (f *Font) useGlyph(gid uint16, t []rune) []byte {
+	if prev, ok := f.glyphs[gid]; !ok || (prev == nil && t != nil) {
+		f.glyphs[gid] = slices.Clone(t)
+	}
+	return []byte{byte(gid >> 8), byte(gid)}
+}
+
+// calculateCIDWidths builds the W array of f's descendant font and the ToUnicode CMap of f.
+func calculateCIDWidths(f *Font) {
+	if f.cid == nil {
+		f.cid = &cidFont{parent: f}
+		f.toUnicode = &stream{Filter: Flate}
+	}
+	gids := make([]uint16, 0, len(f.glyphs))
+	for gid := range f.glyphs {
+		gids = append(gids, gid)
+	}
+	slices.Sort(gids)
+
+	// runs of consecutive glyph IDs are written as c [w1 w2 ... wn]
+	w := make([]byte, 0, 8*len(gids)+2)
+	w = append(w, '[')
+	for i, gid := range gids {
+		if i == 0 || gids[i-1] != gid-1 {
+			if i != 0 {
+				w = append(w, "]\x20"...)
+			}
+			w = itobuf(gid, w)
+			w = append(w, "\x20["...)
+		} else {
+			w = append(w, '\x20')
+		}
+		w = itobuf(f.glyphIDAdvance(gid), w)
+	}
+	if len(gids) != 0 {
+		w = append(w, ']')
+	}
+	f.cid.w = append(w, ']')
+
+	f.toUnicode.buf = toUnicodeCMap(gids, f.glyphs)
+}
+
+// toUnicodeCMap returns a ToUnicode CMap that maps the 2-byte codes of gids to the text contained in m.
+func toUnicodeCMap(gids []uint16, m map[uint16][]rune) []byte {
+	type entry struct {
+		gid uint16
+		t   []byte // UTF-16BE
+	}
+	entries := make([]entry, 0, len(gids))
+	for _, gid := range gids {
+		if len(m[gid]) != 0 {
+			if utf16 == nil {
+				utf16 = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder()
+			}
+			t, err := utf16.Bytes([]byte(string(m[gid])))
+			if err != nil {
+				continue
+			}
+			entries = append(entries, entry{gid, t})
+		}
+	}
+
+	b := make([]byte, 0, 512+24*len(entries))
+	b = append(b, "/CIDInit /ProcSet findresource begin\n"+
+		"12 dict begin\n"+
+		"begincmap\n"+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n"+
+		"/CMapName /Adobe-Identity-UCS def\n"+
+		"/CMapType 2 def\n"+
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n"...)
+	// bfchar sections are limited to 100 entries
+	for len(entries) != 0 {
+		n := min(len(entries), 100)
+		b = strconv.AppendInt(b, int64(n), 10)
+		b = append(b, " beginbfchar\n"...)
+		for _, e := range entries[:n] {
+			b = append(b, htxt([]byte{byte(e.gid >> 8), byte(e.gid)})...)
+			b = append(b, '\x20')
+			b = append(b, htxt(e.t)...)
+			b = append(b, '\n')
+		}
+		b = append(b, "endbfchar\n"...)
+		entries = entries[n:]
+	}
+	return append(b, "endcmap\n"+
+		"CMapName currentdict /CMap defineresource pop\n"+
+		"end\n"+
+		"end"...)
+}
@@ -12,6 +12,8 @@ type ContentStream struct {
 	stack     []stackState // used for recording the order of calls to QSave/QRestore and BeginText/EndText
 	resources resourceDict
 	refnum    int
+	deferred  []deferred // text slots drawn when the PDF is written
+	marks     []mark
 }
 
 type stackState uint8
@@ -0,0 +1,172 @@
+package gdf
+
+import (
+	"slices"
+	"strconv"
+)
+
+// A DeferredFunc returns the text of a deferred text slot on the page described by pi. It is called by PDF.WriteTo, after
+// the PDF's pages are final.
+type DeferredFunc func(pi PageInfo) string
+
+// Alignment specifies how deferred text is aligned within its slot.
+type Alignment uint
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+// A PageInfo describes the position of a page in a PDF when the PDF is written, and the marks set on the PDF's pages.
+type PageInfo struct {
+	Page     int // the 1-based number of the page
+	NumPages int // the number of pages in the PDF
+	marks    [][]mark
+}
+
+type mark struct {
+	name, value string
+}
+
+// a deferred records the text state at a slot reserved by ContentStream.DeferText
+type deferred struct {
+	off       int // the offset of the slot in the ContentStream's buffer
+	font      *Font
+	size      float64
+	wordSpace float64
+	hScale    float64
+	rise      float64
+	width     float64
+	align     Alignment
+	f         DeferredFunc
+}
+
+// DeferText reserves a slot for a line of text that is not known until c's PDF is written, such as the number of pages in
+// the PDF, the number of the page on which a later heading is drawn, or the title of the section to which a page belongs.
+// The slot is width points wide and begins at the current text position. When the PDF is written, its text is returned by
+// f and drawn in the font, font size, word spacing, horizontal scaling, and rise that are current when DeferText is called,
+// and it is aligned within the slot by align. Like ShowString, DeferText must be called within a text object; it advances
+// the text matrix by width. Deferred text is only drawn to the ContentStreams of pages.
+func (c *ContentStream) DeferText(width float64, align Alignment, f DeferredFunc) {
+	c.deferred = append(c.deferred, deferred{
+		off:       len(c.buf),
+		font:      c.Font,
+		size:      c.FontSize,
+		wordSpace: c.WordSpace,
+		hScale:    c.HScale,
+		rise:      c.Rise,
+		width:     width,
+		align:     align,
+		f:         f,
+	})
+	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, width, 0})
+}
+
+// Mark sets a mark with the given name and value on c's page. Marks can be read by the DeferredFuncs of any page; e.g., a
+// running header can show the title of the current section, and a cross-reference can show the number of the page on
+// which a heading is drawn.
+func (c *ContentStream) Mark(name, value string) {
+	c.marks = append(c.marks, mark{name, value})
+}
+
+// Mark returns the value of the first mark with the given name that was set on the page described by pi, or, if there is
+// none, that of the last such mark set on an earlier page. It returns "" if no such mark has been set on or before the page.
+func (pi PageInfo) Mark(name string) string {
+	for i := pi.Page - 1; i >= 0 && i < len(pi.marks); i-- {
+		if i == pi.Page-1 {
+			for _, m := range pi.marks[i] {
+				if m.name == name {
+					return m.value
+				}
+			}
+			continue
+		}
+		for j := len(pi.marks[i]) - 1; j >= 0; j-- {
+			if m := pi.marks[i][j]; m.name == name {
+				return m.value
+			}
+		}
+	}
+	return ""
+}
+
+// MarkPage returns the 1-based number of the first page on which a mark with the given name and value was set, or 0 if
+// there is none.
+func (pi PageInfo) MarkPage(name, value string) int {
+	for i, marks := range pi.marks {
+		if slices.Contains(marks, mark{name, value}) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// resolveDeferred draws the deferred text of each of pdf's pages. It must be called before pdf's fonts are finalized.
+func resolveDeferred(pdf *PDF) error {
+	pages := pdf.catalog.pages.P
+	pi := PageInfo{NumPages: len(pages), marks: make([][]mark, len(pages))}
+	for i, p := range pages {
+		if p.c != nil {
+			pi.marks[i] = p.c.marks
+		}
+	}
+	for i, p := range pages {
+		if p.c == nil || len(p.c.deferred) == 0 {
+			continue
+		}
+		pi.Page = i + 1
+		c := p.c
+		buf := make([]byte, 0, len(c.buf)+64*len(c.deferred))
+		var prev int
+		for _, d := range c.deferred {
+			b, err := d.draw(pi)
+			if err != nil {
+				return err
+			}
+			buf = append(buf, c.buf[prev:d.off]...)
+			buf = append(buf, b...)
+			prev = d.off
+		}
+		c.buf = append(buf, c.buf[prev:]...)
+		c.deferred = nil
+	}
+	return nil
+}
+
+// draw returns the operations that draw the text of d on the page described by pi. The text is positioned within d's slot
+// by TJ adjustments, so that the text matrix is advanced by exactly the slot's width and the line matrix is unchanged.
+func (d deferred) draw(pi PageInfo) ([]byte, error) {
+	glyphs, err := d.font.Shape([]rune(d.f(pi)), nil)
+	if err != nil {
+		return nil, err
+	}
+	tmp := ContentStream{TextObj: &TextObj{Matrix: NewMatrix(), LineMatrix: NewMatrix()}}
+	tmp.Font, tmp.FontSize, tmp.WordSpace, tmp.HScale, tmp.Rise = d.font, d.size, d.wordSpace, d.hScale, d.rise
+	tmp.ShowGlyphs(glyphs)
+	// the extent of the text, which ShowGlyphs added to the text matrix
+	ext := tmp.TextObj.Matrix.E
+	var shift float64
+	switch d.align {
+	case AlignRight:
+		shift = d.width - ext
+	case AlignCenter:
+		shift = (d.width - ext) / 2
+	}
+	// TJ adjustments are in thousandths of a text space unit, and are scaled by the font size and horizontal scaling
+	toTJ := func(x float64) float64 {
+		if d.size == 0 || d.hScale == 0 {
+			return 0
+		}
+		return -PtToFU(x, d.size) * 100 / d.hScale
+	}
+	out := make([]byte, 0, len(tmp.buf)+32)
+	out = append(out, '[')
+	out = strconv.AppendFloat(out, toTJ(shift), 'f', -1, 64)
+	out = append(out, "] TJ\n"...)
+	out = append(out, tmp.buf...)
+	out = append(out, '[')
+	out = strconv.AppendFloat(out, toTJ(d.width-shift-ext), 'f', -1, 64)
+	out = append(out, "] TJ\n"...)
+	return out, nil
+}
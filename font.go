@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/cdillond/gdf/subset"
+	"github.com/go-text/typesetting/harfbuzz"
 	"golang.org/x/image/font/sfnt"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
@@ -22,6 +23,15 @@ type FontSubsetter interface {
 	Subset(cutset map[rune]struct{}) ([]byte, error)
 }
 
+// A GlyphSubsetter is a FontSubsetter that can also retain glyphs that are not reachable from any rune in the cutset.
+// When a composite Font is finalized, the IDs of all of the glyphs drawn with it, including those chosen by Font.Shape, are
+// passed to SubsetGlyphs if the Font's Subsetter implements this interface. Otherwise, only the Subset method is called.
+// A GlyphSubsetter should not alter the ID of any glyph in gset.
+type GlyphSubsetter interface {
+	FontSubsetter
+	SubsetGlyphs(cutset map[rune]struct{}, gset map[uint16]struct{}) ([]byte, error)
+}
+
 type DefaultSubsetter struct {
 	subset.BasicSubsetter
 }
@@ -33,6 +43,11 @@ const ppem = 1000
 type Font struct {
 	SFNT      *sfnt.Font // The source TrueType or OpenType font.
 	Subsetter FontSubsetter
+	// If Composite is true, text drawn in the Font is encoded by glyph ID, and the Font is embedded in the output PDF as a
+	// composite (Type0) font with an Identity-H encoding instead of as a simple TrueType font with WinAnsiEncoding.
+	// Only composite fonts can draw glyphs that are not mapped to Windows-1252 characters, such as ligatures, alternates, and
+	// the glyphs of most non-Latin scripts. Composite must be set before the Font is first used.
+	Composite bool
 
 	*simpleFD
 
@@ -49,6 +64,12 @@ type Font struct {
 	buf       *sfnt.Buffer
 	srcb      []byte
 	srcPath   string
+	glyphs    map[uint16][]rune // maps the glyphs drawn with a composite font to the text they represent
+	cid       *cidFont
+	toUnicode *stream
+	shaper    *harfbuzz.Font
+	kerner    *pairKerner // GPOS pair kerning; nil if the font has none
+	kernInit  bool
 }
 
 // LoadSFNT returns a *Font object, which can be used for drawing text to a ContentStream or XObject, and an error.
@@ -63,6 +84,7 @@ func LoadSFNT(b []byte, flag FontFlag) (*Font, error) {
 		subtype:   "/TrueType",
 		encName:   "/WinAnsiEncoding",
 		charset:   make(map[rune]int),
+		glyphs:    make(map[uint16][]rune),
 		enc:       charmap.Windows1252.NewEncoder(),
 		source: &stream{
 			Filter: Flate,
@@ -96,6 +118,38 @@ func LoadSFNTFile(path string, flag FontFlag) (*Font, error) {
 	return f, err
 }
 
+// LoadSFNTCollection returns a *Font object derived from the face at the given index of the TrueType/OpenType collection
+// (.ttc/.otc) b, and an error. The selected face is extracted into a standalone SFNT, which is what gets passed to the Font's
+// FontSubsetter and embedded in the output PDF. Use CollectionFaces to find the index of a particular face. If b is a
+// single font rather than a collection, index must be 0.
+func LoadSFNTCollection(b []byte, index int, flag FontFlag) (*Font, error) {
+	src, err := extractSFNT(b, index)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSFNT(src, flag)
+}
+
+// CollectionFaces returns the PostScript names of the faces contained in the TrueType/OpenType collection b, in index order.
+func CollectionFaces(b []byte) ([]string, error) {
+	c, err := sfnt.ParseCollection(b)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(sfnt.Buffer)
+	out := make([]string, c.NumFonts())
+	for i := range out {
+		fnt, err := c.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		if out[i], err = fnt.Name(buf, sfnt.NameIDPostScript); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
 type simpleFD struct {
 	FontName    string
 	Flags       FontFlag
@@ -162,9 +216,22 @@ const (
 func (f *Font) mark(i int) { f.refnum = i }
 func (f *Font) id() int    { return f.refnum }
 func (f *Font) children() []obj {
+	if f.Composite {
+		return []obj{f.cid, f.toUnicode, f.simpleFD, f.source}
+	}
 	return []obj{f.simpleFD, f.source}
 }
 func (f *Font) encode(w io.Writer) (int, error) {
+	if f.Composite {
+		return w.Write(dict(256, []field{
+			{"/Type", "/Font"},
+			{"/Subtype", "/Type0"},
+			{"/BaseFont", f.baseFont},
+			{"/Encoding", "/Identity-H"},
+			{"/DescendantFonts", []obj{f.cid}},
+			{"/ToUnicode", iref(f.toUnicode)},
+		}))
+	}
 	return w.Write(dict(1024, []field{
 		{"/Type", "/Font"},
 		{"/Subtype", f.subtype},
@@ -1,6 +1,13 @@
 package gdf
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	gtfont "github.com/go-text/typesetting/font"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
@@ -28,6 +35,20 @@ func calculateWidths(f *Font) {
 	f.widths = charWidths[f.firstChar : f.lastChar+1]
 }
 
+// HasRune reports whether f can draw r: f's character map must map r to a glyph other than .notdef, and, unless f is
+// Composite, r must be one of the characters of the Windows-1252 encoding in which f's text is written.
+func (f *Font) HasRune(r rune) bool {
+	gid, err := f.SFNT.GlyphIndex(f.buf, r)
+	if err != nil || gid == 0 {
+		return false
+	}
+	if !f.Composite {
+		b, err := f.enc.Bytes([]byte(string(r)))
+		return err == nil && len(b) != 0
+	}
+	return true
+}
+
 // GlyphAdvance returns the advance of r in font units.
 func (f *Font) GlyphAdvance(r rune) int {
 	adv, ok := f.charset[r]
@@ -50,7 +71,9 @@ func (f *Font) GlyphAdvance(r rune) int {
 	return int(adv26_6)
 }
 
-// ShapedGlyphAdv returns the advance and kerning of r1 when set before r2.
+// ShapedGlyphAdv returns the advance and kerning of r1 when set before r2. Kerning is taken from the pair adjustment lookups
+// of the 'kern' feature in the font's GPOS table, including class-based pairs, or, if the GPOS table has no such feature,
+// from the font's legacy kern table.
 func (f *Font) ShapedGlyphAdv(r1, r2 rune) (adv int, kern int) {
 	adv = f.GlyphAdvance(r1)
 	gid1, err := f.SFNT.GlyphIndex(f.buf, r1)
@@ -61,6 +84,9 @@ func (f *Font) ShapedGlyphAdv(r1, r2 rune) (adv int, kern int) {
 	if err != nil {
 		return adv, 0
 	}
+	if pk := f.gposKerner(); pk != nil {
+		return adv, pk.kern(uint16(gid1), uint16(gid2))
+	}
 	fpKern, err := f.SFNT.Kern(f.buf, gid1, gid2, 1000, 0)
 	if err != nil {
 		return adv, 0
@@ -68,6 +94,16 @@ func (f *Font) ShapedGlyphAdv(r1, r2 rune) (adv int, kern int) {
 	return adv, int(fpKern)
 }
 
+// Kerns returns the kerning, in font units, of each rune in t when set before the rune that follows it, in a form
+// suitable for use with ContentStream.ShowText and ContentStream.ExtentKerns. The last rune in t is not kerned.
+func (f *Font) Kerns(t []rune) []int {
+	out := make([]int, len(t))
+	for i := 0; i < len(t)-1; i++ {
+		_, out[i] = f.ShapedGlyphAdv(t[i], t[i+1])
+	}
+	return out
+}
+
 func fontBBox(font *sfnt.Font, buf *sfnt.Buffer) (fixed.Rectangle26_6, error) {
 	bbox := *new(fixed.Rectangle26_6)
 	for i := sfnt.GlyphIndex(0); i < sfnt.GlyphIndex(font.NumGlyphs()); i++ {
@@ -105,3 +141,139 @@ func (f *Font) TextAscDesc(text []rune) (asc float64, desc float64) {
 	}
 	return asc, desc
 }
+
+// UnderlineMetrics returns the position and thickness, in font units, of the underline suggested by f's post table. The position
+// is the distance from the baseline to the top of the underline, which is negative if the underline is below the baseline.
+// If f does not specify an underline, a line 50 units thick whose top is 100 units below the baseline is returned.
+func (f *Font) UnderlineMetrics() (pos, thickness float64) {
+	return f.lineMetrics(gtfont.UnderlinePosition, gtfont.UnderlineThickness, -100, 50)
+}
+
+// StrikeoutMetrics returns the position and thickness, in font units, of the strikeout stroke suggested by f's OS/2 table. The
+// position is the distance from the baseline to the top of the stroke. If f does not specify a strikeout stroke, one with the
+// thickness of its underline, centered half an x-height above the baseline, is returned.
+func (f *Font) StrikeoutMetrics() (pos, thickness float64) {
+	_, thickness = f.UnderlineMetrics()
+	xh := float64(f.XHeight)
+	if xh <= 0 {
+		xh = 500
+	}
+	return f.lineMetrics(gtfont.StrikethroughPosition, gtfont.StrikethroughThickness, (xh+thickness)/2, thickness)
+}
+
+// lineMetrics returns f's position and thickness metrics, scaled to font units, or defPos and defThickness if f's thickness
+// metric is not positive.
+func (f *Font) lineMetrics(pos, thickness gtfont.LineMetric, defPos, defThickness float64) (float64, float64) {
+	hb, err := f.hbFont()
+	if err != nil {
+		return defPos, defThickness
+	}
+	face := hb.Face()
+	scale := float64(ppem) / float64(face.Upem())
+	p, t := float64(face.LineMetric(pos))*scale, float64(face.LineMetric(thickness))*scale
+	if t <= 0 {
+		return defPos, defThickness
+	}
+	return p, t
+}
+
+// extractSFNT returns the source bytes of a standalone SFNT containing the tables of the face at index i of the font collection src.
+// If src is not a collection, it is returned unaltered, provided that i is 0.
+func extractSFNT(src []byte, i int) ([]byte, error) {
+	if len(src) < 12 || string(src[:4]) != "ttcf" {
+		if i != 0 {
+			return nil, fmt.Errorf("font index %d out of range; source is not a font collection", i)
+		}
+		return src, nil
+	}
+	numFonts := int(binary.BigEndian.Uint32(src[8:]))
+	if i < 0 || i >= numFonts {
+		return nil, fmt.Errorf("font index %d out of range; collection contains %d fonts", i, numFonts)
+	}
+	if len(src) < 12+4*numFonts {
+		return nil, errors.New("invalid font collection header")
+	}
+	offset := int(binary.BigEndian.Uint32(src[12+4*i:]))
+	if offset+12 > len(src) {
+		return nil, errors.New("invalid table directory offset")
+	}
+	numTables := int(binary.BigEndian.Uint16(src[offset+4:]))
+	if offset+12+16*numTables > len(src) {
+		return nil, errors.New("invalid table directory")
+	}
+	// Table offsets in a collection are relative to the start of the file, so each table's data must be relocated.
+	tables := make([]sfntTable, numTables)
+	for j := range tables {
+		rec := src[offset+12+16*j:]
+		tOffset := int(binary.BigEndian.Uint32(rec[8:]))
+		tLen := int(binary.BigEndian.Uint32(rec[12:]))
+		if tOffset < 0 || tLen < 0 || tOffset+tLen > len(src) {
+			return nil, errors.New("invalid table record")
+		}
+		tables[j] = sfntTable{tag: string(rec[:4]), data: src[tOffset : tOffset+tLen]}
+	}
+	return writeSFNT(src[offset:offset+4], tables), nil
+}
+
+type sfntTable struct {
+	tag  string
+	data []byte
+}
+
+// writeSFNT returns the bytes of an SFNT with the given sfnt version (e.g. "\x00\x01\x00\x00" or "OTTO") that contains tables.
+// The head table's checksumAdjustment is recomputed.
+func writeSFNT(version []byte, tables []sfntTable) []byte {
+	slices.SortFunc(tables, func(a, b sfntTable) int { return strings.Compare(a.tag, b.tag) })
+	numTables := len(tables)
+	dirLen := 12 + 16*numTables
+	size := dirLen
+	for _, t := range tables {
+		size += (len(t.data) + 3) &^ 3
+	}
+	out := make([]byte, dirLen, size)
+	copy(out, version)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	var entrySelector uint16
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := uint16(16 << entrySelector)
+	binary.BigEndian.PutUint16(out[6:], searchRange)
+	binary.BigEndian.PutUint16(out[8:], entrySelector)
+	binary.BigEndian.PutUint16(out[10:], uint16(16*numTables)-searchRange)
+
+	var headOffset = -1
+	for j, t := range tables {
+		r := 12 + 16*j
+		offset := len(out)
+		out = append(out, t.data...)
+		// tables must be 4-byte aligned
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+		if t.tag == "head" && len(t.data) >= 12 {
+			// the head table's checksum is calculated with a checksumAdjustment of 0
+			headOffset = offset
+			binary.BigEndian.PutUint32(out[offset+8:], 0)
+		}
+		copy(out[r:], t.tag)
+		binary.BigEndian.PutUint32(out[r+4:], checksum(out[offset:]))
+		binary.BigEndian.PutUint32(out[r+8:], uint32(offset))
+		binary.BigEndian.PutUint32(out[r+12:], uint32(len(t.data)))
+	}
+
+	// The head table's checksumAdjustment depends on the contents of the entire file.
+	if headOffset > -1 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-checksum(out))
+	}
+	return out
+}
+
+// checksum returns the SFNT checksum of b, which must be padded to a multiple of 4 bytes.
+func checksum(b []byte) uint32 {
+	var sum uint32
+	for j := 0; j+4 <= len(b); j += 4 {
+		sum += binary.BigEndian.Uint32(b[j:])
+	}
+	return sum
+}
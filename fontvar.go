@@ -0,0 +1,545 @@
+package gdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	gtfont "github.com/go-text/typesetting/font"
+	loader "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/font/sfnt"
+)
+
+// A Variation specifies the design-space value of one of a variable font's axes.
+type Variation struct {
+	Axis  string  // The axis tag, e.g. "wght" or "wdth".
+	Value float64 // The design-space value for the axis, e.g. 650 for a "wght" axis.
+}
+
+// A NamedInstance is a predefined set of axis values listed in a variable font's fvar table.
+type NamedInstance struct {
+	Name           string // The instance's subfamily name, e.g. "Bold" or "Condensed Italic".
+	PostScriptName string // The instance's PostScript name, if the font provides one.
+	Variations     []Variation
+}
+
+// ErrNotVariable is returned when a variable font operation is attempted on a font that has no variation axes.
+var ErrNotVariable = errors.New("font is not a variable font")
+
+// LoadSFNTInstance returns a *Font object representing a static instance of the variable TrueType font b at the given
+// axis values, and an error. Axes that are not listed in vars take their default values; values outside of an axis's
+// range are clamped. The instance's glyph outlines and metrics are computed by applying the font's gvar, HVAR, and MVAR
+// deltas, and the fvar, gvar, avar, and other variation tables are omitted from the embedded font. Glyph positioning
+// data in GPOS tables is retained as-is, so kerning reflects the font's default instance. Only fonts with TrueType (glyf)
+// outlines are supported.
+func LoadSFNTInstance(b []byte, vars []Variation, flag FontFlag) (*Font, error) {
+	src, psName, err := instantiate(b, vars)
+	if err != nil {
+		return nil, err
+	}
+	f, err := LoadSFNT(src, flag)
+	if err != nil {
+		return nil, err
+	}
+	f.baseFont = name(psName)
+	f.simpleFD.FontName = f.baseFont
+	return f, nil
+}
+
+// LoadSFNTNamedInstance is like LoadSFNTInstance, but the axis values are taken from the named instance of b whose
+// Name or PostScriptName matches instance.
+func LoadSFNTNamedInstance(b []byte, instance string, flag FontFlag) (*Font, error) {
+	instances, err := NamedInstances(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, ni := range instances {
+		if ni.Name == instance || (ni.PostScriptName != "" && ni.PostScriptName == instance) {
+			return LoadSFNTInstance(b, ni.Variations, flag)
+		}
+	}
+	return nil, fmt.Errorf("variable font does not contain a named instance %q", instance)
+}
+
+// NamedInstances returns the named instances defined in the variable font b.
+func NamedInstances(b []byte) ([]NamedInstance, error) {
+	ld, err := loader.NewLoader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	fv, err := parseFvar(ld)
+	if err != nil {
+		return nil, err
+	}
+	nameRaw, _ := ld.RawTable(loader.MustNewTag("name"))
+	names, _, _ := tables.ParseName(nameRaw)
+
+	out := make([]NamedInstance, len(fv.instances))
+	for i, inst := range fv.instances {
+		ni := NamedInstance{
+			Name:       names.Name(tables.NameID(inst.nameID)),
+			Variations: make([]Variation, len(fv.axes)),
+		}
+		if inst.psNameID != 0 && inst.psNameID != 0xFFFF {
+			ni.PostScriptName = names.Name(tables.NameID(inst.psNameID))
+		}
+		for j, axis := range fv.axes {
+			ni.Variations[j] = Variation{Axis: axis.tag, Value: inst.coords[j]}
+		}
+		out[i] = ni
+	}
+	return out, nil
+}
+
+type fvarAxis struct {
+	tag           string
+	min, def, max float64
+}
+
+type fvarInstance struct {
+	nameID, psNameID uint16
+	coords           []float64
+}
+
+type fvarTable struct {
+	axes      []fvarAxis
+	instances []fvarInstance
+}
+
+// parseFvar parses the axis and instance records of the font's fvar table.
+func parseFvar(ld *loader.Loader) (fvarTable, error) {
+	raw, err := ld.RawTable(loader.MustNewTag("fvar"))
+	if err != nil {
+		return fvarTable{}, ErrNotVariable
+	}
+	if len(raw) < 16 {
+		return fvarTable{}, errors.New("invalid fvar table")
+	}
+	axesOffset := int(binary.BigEndian.Uint16(raw[4:]))
+	axisCount := int(binary.BigEndian.Uint16(raw[8:]))
+	axisSize := int(binary.BigEndian.Uint16(raw[10:]))
+	instanceCount := int(binary.BigEndian.Uint16(raw[12:]))
+	instanceSize := int(binary.BigEndian.Uint16(raw[14:]))
+	if axisCount == 0 {
+		return fvarTable{}, ErrNotVariable
+	}
+	instOffset := axesOffset + axisCount*axisSize
+	if axisSize < 20 || instanceSize < 4+4*axisCount || instOffset+instanceCount*instanceSize > len(raw) {
+		return fvarTable{}, errors.New("invalid fvar table")
+	}
+	fixed := func(b []byte) float64 { return float64(int32(binary.BigEndian.Uint32(b))) / 65536 }
+
+	out := fvarTable{axes: make([]fvarAxis, axisCount), instances: make([]fvarInstance, instanceCount)}
+	for i := range out.axes {
+		rec := raw[axesOffset+i*axisSize:]
+		out.axes[i] = fvarAxis{tag: string(rec[:4]), min: fixed(rec[4:]), def: fixed(rec[8:]), max: fixed(rec[12:])}
+	}
+	for i := range out.instances {
+		rec := raw[instOffset+i*instanceSize:]
+		inst := fvarInstance{nameID: binary.BigEndian.Uint16(rec), coords: make([]float64, axisCount)}
+		for j := range inst.coords {
+			inst.coords[j] = fixed(rec[4+4*j:])
+		}
+		if instanceSize >= 6+4*axisCount {
+			inst.psNameID = binary.BigEndian.Uint16(rec[4+4*axisCount:])
+		}
+		out.instances[i] = inst
+	}
+	return out, nil
+}
+
+// tables that are either invalidated by instancing or that only apply to variable fonts
+var droppedVarTables = map[string]bool{
+	"fvar": true, "gvar": true, "avar": true, "cvar": true, "HVAR": true, "VVAR": true, "MVAR": true, "STAT": true,
+	"cvt ": true, "fpgm": true, "prep": true, "hdmx": true, "LTSH": true, "VDMX": true, "DSIG": true, "vmtx": true, "vhea": true,
+}
+
+// instantiate returns the source bytes of a static TrueType font derived from the variable font src at the given axis values,
+// along with a PostScript name for the instance.
+func instantiate(src []byte, vars []Variation) ([]byte, string, error) {
+	ld, err := loader.NewLoader(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", err
+	}
+	fv, err := parseFvar(ld)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ld.HasTable(loader.MustNewTag("glyf")) {
+		return nil, "", errors.New("only variable fonts with TrueType outlines can be instanced")
+	}
+	fnt, err := gtfont.NewFont(ld)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// resolve the design-space coordinates of the instance
+	design := make([]float64, len(fv.axes))
+	gtVars := make([]gtfont.Variation, 0, len(fv.axes))
+	for i, axis := range fv.axes {
+		design[i] = axis.def
+		for _, v := range vars {
+			if v.Axis == axis.tag {
+				design[i] = math.Max(axis.min, math.Min(axis.max, v.Value))
+			}
+		}
+		gtVars = append(gtVars, gtfont.Variation{Tag: loader.MustNewTag(axis.tag), Value: float32(design[i])})
+	}
+	face := gtfont.NewFace(fnt)
+	face.SetVariations(gtVars)
+	coords := face.Coords()
+
+	raw := make(map[string][]byte)
+	for _, tag := range ld.Tables() {
+		b, err := ld.RawTable(tag)
+		if err != nil {
+			return nil, "", err
+		}
+		// copy the table, since some tables are modified below
+		raw[tag.String()] = append([]byte(nil), b...)
+	}
+	// the minimum lengths of the tables whose fields are read or written below
+	for _, t := range []struct {
+		tag string
+		n   int
+	}{{"head", 54}, {"hhea", 36}, {"maxp", 6}} {
+		if len(raw[t.tag]) < t.n {
+			return nil, "", fmt.Errorf("invalid or missing %s table", t.tag)
+		}
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(raw["maxp"][4:]))
+
+	// MVAR deltas
+	var mvar tables.MVAR
+	if b, ok := raw["MVAR"]; ok {
+		mvar, _, _ = tables.ParseMVAR(b)
+	}
+	mdelta := func(tag string) float32 {
+		t := loader.MustNewTag(tag)
+		for _, rec := range mvar.ValueRecords {
+			if rec.ValueTag == t {
+				return mvar.ItemVariationStore.GetDelta(rec.Index, coords)
+			}
+		}
+		return 0
+	}
+	addI16 := func(b []byte, off int, tag string) {
+		if off+2 > len(b) {
+			return
+		}
+		v := float32(int16(binary.BigEndian.Uint16(b[off:]))) + mdelta(tag)
+		binary.BigEndian.PutUint16(b[off:], uint16(int16(math.Round(float64(v)))))
+	}
+	addU16 := func(b []byte, off int, tag string) {
+		if off+2 > len(b) {
+			return
+		}
+		v := float32(binary.BigEndian.Uint16(b[off:])) + mdelta(tag)
+		binary.BigEndian.PutUint16(b[off:], uint16(math.Round(math.Max(0, float64(v)))))
+	}
+
+	// outlines and horizontal metrics
+	glyf := make([]byte, 0, len(raw["glyf"]))
+	loca := make([]byte, 4*(numGlyphs+1))
+	hmtx := make([]byte, 4*numGlyphs)
+	var maxPoints, maxContours int
+	var advMax int
+	minLSB, minRSB, xMaxExt := math.MaxInt16, math.MaxInt16, math.MinInt16
+	bbox := [4]int{math.MaxInt16, math.MaxInt16, math.MinInt16, math.MinInt16}
+	for gid := 0; gid < numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[4*gid:], uint32(len(glyf)))
+		var segs []loader.Segment
+		if outline, ok := face.GlyphData(gtfont.GID(gid)).(gtfont.GlyphOutline); ok {
+			segs = outline.Segments
+		}
+		g, ext, nPts, nContours := encodeSimpleGlyph(segs)
+		glyf = append(glyf, g...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+		adv := int(math.Round(float64(face.HorizontalAdvance(gtfont.GID(gid)))))
+		if adv < 0 {
+			adv = 0
+		}
+		var lsb int
+		if nContours > 0 {
+			lsb = ext[0]
+			minLSB = min(minLSB, ext[0])
+			minRSB = min(minRSB, adv-ext[2])
+			xMaxExt = max(xMaxExt, ext[2])
+			bbox = [4]int{min(bbox[0], ext[0]), min(bbox[1], ext[1]), max(bbox[2], ext[2]), max(bbox[3], ext[3])}
+		}
+		binary.BigEndian.PutUint16(hmtx[4*gid:], uint16(adv))
+		binary.BigEndian.PutUint16(hmtx[4*gid+2:], uint16(int16(lsb)))
+		advMax = max(advMax, adv)
+		maxPoints = max(maxPoints, nPts)
+		maxContours = max(maxContours, nContours)
+	}
+	binary.BigEndian.PutUint32(loca[4*numGlyphs:], uint32(len(glyf)))
+	if bbox[0] > bbox[2] {
+		bbox = [4]int{}
+		minLSB, minRSB, xMaxExt = 0, 0, 0
+	}
+
+	head := raw["head"]
+	for i, v := range bbox {
+		binary.BigEndian.PutUint16(head[36+2*i:], uint16(int16(v)))
+	}
+	binary.BigEndian.PutUint16(head[50:], 1) // long loca offsets
+
+	hhea := raw["hhea"]
+	binary.BigEndian.PutUint16(hhea[10:], uint16(advMax))
+	binary.BigEndian.PutUint16(hhea[12:], uint16(int16(minLSB)))
+	binary.BigEndian.PutUint16(hhea[14:], uint16(int16(minRSB)))
+	binary.BigEndian.PutUint16(hhea[16:], uint16(int16(xMaxExt)))
+	addI16(hhea, 18, "hcrs")
+	addI16(hhea, 20, "hcrn")
+	addI16(hhea, 22, "hcof")
+	binary.BigEndian.PutUint16(hhea[34:], uint16(numGlyphs))
+
+	maxp := raw["maxp"]
+	if len(maxp) >= 32 {
+		binary.BigEndian.PutUint16(maxp[6:], uint16(maxPoints))
+		binary.BigEndian.PutUint16(maxp[8:], uint16(maxContours))
+		binary.BigEndian.PutUint16(maxp[10:], 0) // composite glyphs are decomposed
+		binary.BigEndian.PutUint16(maxp[12:], 0)
+		binary.BigEndian.PutUint16(maxp[26:], 0) // instructions are removed
+		binary.BigEndian.PutUint16(maxp[28:], 0)
+		binary.BigEndian.PutUint16(maxp[30:], 0)
+	}
+
+	if os2 := raw["OS/2"]; len(os2) >= 78 {
+		for i, axis := range fv.axes {
+			switch axis.tag {
+			case "wght":
+				binary.BigEndian.PutUint16(os2[4:], uint16(math.Max(1, math.Min(1000, math.Round(design[i])))))
+			case "wdth":
+				binary.BigEndian.PutUint16(os2[6:], widthClass(design[i]))
+			}
+		}
+		for i, tag := range []string{"sbxs", "sbys", "sbxo", "sbyo", "spxs", "spys", "spxo", "spyo", "strs", "stro"} {
+			addI16(os2, 10+2*i, tag)
+		}
+		addI16(os2, 68, "hasc")
+		addI16(os2, 70, "hdsc")
+		addI16(os2, 72, "hlgp")
+		addU16(os2, 74, "hcla")
+		addU16(os2, 76, "hcld")
+		if binary.BigEndian.Uint16(os2) >= 2 {
+			addI16(os2, 86, "xhgt")
+			addI16(os2, 88, "cpht")
+		}
+	}
+	if post := raw["post"]; len(post) >= 12 {
+		addI16(post, 8, "undo")
+		addI16(post, 10, "unds")
+	}
+
+	out := make([]sfntTable, 0, len(raw))
+	for tag, b := range raw {
+		if droppedVarTables[tag] {
+			continue
+		}
+		switch tag {
+		case "glyf":
+			b = glyf
+		case "loca":
+			b = loca
+		case "hmtx":
+			b = hmtx
+		}
+		out = append(out, sfntTable{tag: tag, data: b})
+	}
+
+	// derive a PostScript name for the instance
+	names, _, _ := tables.ParseName(raw["name"])
+	psName := names.Name(tables.NameID(sfnt.NameIDPostScript))
+	var named bool
+	for _, inst := range fv.instances {
+		match := true
+		for j := range inst.coords {
+			if math.Abs(inst.coords[j]-design[j]) > 1e-3 {
+				match = false
+				break
+			}
+		}
+		if match && inst.psNameID != 0 && inst.psNameID != 0xFFFF {
+			if s := names.Name(tables.NameID(inst.psNameID)); s != "" {
+				psName, named = s, true
+				break
+			}
+		}
+	}
+	if !named {
+		if name := instancePSName(names, fv.axes, design); name != "" {
+			psName = name
+		}
+	}
+	return writeSFNT([]byte{0, 1, 0, 0}, out), psName, nil
+}
+
+// instancePSName returns the PostScript name of the instance of a variable font at the design coordinates design, as
+// generated for an arbitrary instance by the OpenType spec: the font's Variations PostScript Name Prefix, or, if it has
+// none, its family name without any characters other than ASCII letters and digits, followed by the value and tag of
+// each axis whose value is not its default. It returns "" if the instance is the default instance or the name cannot be
+// generated.
+func instancePSName(names tables.Name, axes []fvarAxis, design []float64) string {
+	prefix := names.Name(tables.NameID(sfnt.NameIDVariationsPostScriptPrefix))
+	if prefix == "" {
+		family := names.Name(tables.NameID(sfnt.NameIDTypographicFamily))
+		if family == "" {
+			family = names.Name(tables.NameID(sfnt.NameIDFamily))
+		}
+		prefix = strings.Map(func(r rune) rune {
+			if ('A' <= r && r <= 'Z') || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return -1
+		}, family)
+	}
+	if prefix == "" {
+		return ""
+	}
+	var bldr strings.Builder
+	bldr.WriteString(prefix)
+	for i, axis := range axes {
+		if design[i] == axis.def {
+			continue
+		}
+		bldr.WriteByte('_')
+		bldr.WriteString(strconv.FormatFloat(math.Round(design[i]*1e5)/1e5, 'f', -1, 64))
+		bldr.WriteString(strings.TrimRight(axis.tag, " "))
+	}
+	if bldr.Len() == len(prefix) {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' || strings.ContainsRune("[](){}<>/%", r) {
+			return -1
+		}
+		return r
+	}, bldr.String())
+}
+
+// widthClass maps a wdth axis value (a percentage of normal width) to an OS/2 usWidthClass value.
+func widthClass(wdth float64) uint16 {
+	thresholds := [...]float64{50, 62.5, 75, 87.5, 100, 112.5, 125, 150, 200}
+	for i := len(thresholds) - 1; i > 0; i-- {
+		if wdth >= (thresholds[i]+thresholds[i-1])/2 {
+			return uint16(i + 1)
+		}
+	}
+	return 1
+}
+
+// encodeSimpleGlyph returns the glyf table encoding of the quadratic outline described by segs as a simple glyph without
+// instructions, along with the glyph's bounding box, point count, and contour count. Empty outlines return a nil slice.
+func encodeSimpleGlyph(segs []loader.Segment) (b []byte, bbox [4]int, numPoints, numContours int) {
+	type point struct {
+		x, y    int
+		onCurve bool
+	}
+	var pts []point
+	var endPts []int
+	contourStart := 0
+	closeContour := func() {
+		if len(pts) == contourStart {
+			return
+		}
+		// the final point of each contour duplicates its starting point
+		if last, first := pts[len(pts)-1], pts[contourStart]; len(pts)-contourStart > 1 && last == first {
+			pts = pts[:len(pts)-1]
+		}
+		endPts = append(endPts, len(pts)-1)
+		contourStart = len(pts)
+	}
+	rnd := func(p loader.SegmentPoint, onCurve bool) point {
+		return point{int(math.Round(float64(p.X))), int(math.Round(float64(p.Y))), onCurve}
+	}
+	for _, seg := range segs {
+		switch seg.Op {
+		case loader.SegmentOpMoveTo:
+			closeContour()
+			pts = append(pts, rnd(seg.Args[0], true))
+		case loader.SegmentOpLineTo:
+			pts = append(pts, rnd(seg.Args[0], true))
+		case loader.SegmentOpQuadTo:
+			pts = append(pts, rnd(seg.Args[0], false), rnd(seg.Args[1], true))
+		case loader.SegmentOpCubeTo:
+			// not produced by glyf outlines; approximate with the end point
+			pts = append(pts, rnd(seg.Args[2], true))
+		}
+	}
+	closeContour()
+	if len(pts) == 0 {
+		return nil, bbox, 0, 0
+	}
+
+	bbox = [4]int{pts[0].x, pts[0].y, pts[0].x, pts[0].y}
+	for _, p := range pts {
+		bbox = [4]int{min(bbox[0], p.x), min(bbox[1], p.y), max(bbox[2], p.x), max(bbox[3], p.y)}
+	}
+
+	b = make([]byte, 10, 12+2*len(endPts)+5*len(pts))
+	binary.BigEndian.PutUint16(b, uint16(len(endPts)))
+	for i, v := range bbox {
+		binary.BigEndian.PutUint16(b[2+2*i:], uint16(int16(v)))
+	}
+	for _, e := range endPts {
+		b = binary.BigEndian.AppendUint16(b, uint16(e))
+	}
+	b = binary.BigEndian.AppendUint16(b, 0) // instructionLength
+
+	const (
+		onCurve  = 0x01
+		xShort   = 0x02
+		yShort   = 0x04
+		xSamePos = 0x10
+		ySamePos = 0x20
+	)
+	flags := make([]byte, len(pts))
+	var xs, ys []byte
+	var px, py int
+	for i, p := range pts {
+		var f byte
+		if p.onCurve {
+			f |= onCurve
+		}
+		dx, dy := p.x-px, p.y-py
+		switch {
+		case dx == 0:
+			f |= xSamePos
+		case dx > -256 && dx < 256:
+			f |= xShort
+			if dx > 0 {
+				f |= xSamePos
+			}
+			xs = append(xs, byte(abs(dx)))
+		default:
+			xs = binary.BigEndian.AppendUint16(xs, uint16(int16(dx)))
+		}
+		switch {
+		case dy == 0:
+			f |= ySamePos
+		case dy > -256 && dy < 256:
+			f |= yShort
+			if dy > 0 {
+				f |= ySamePos
+			}
+			ys = append(ys, byte(abs(dy)))
+		default:
+			ys = binary.BigEndian.AppendUint16(ys, uint16(int16(dy)))
+		}
+		flags[i] = f
+		px, py = p.x, p.y
+	}
+	b = append(b, flags...)
+	b = append(b, xs...)
+	b = append(b, ys...)
+	return b, bbox, len(pts), len(endPts)
+}
@@ -32,6 +32,9 @@ func buildPDFTree(pdf *PDF) error {
 
 // Builds the PDF and writes it to w.
 func (p *PDF) WriteTo(w io.Writer) (int64, error) {
+	if err := resolveDeferred(p); err != nil {
+		return 0, err
+	}
 	if err := buildPDFTree(p); err != nil {
 		return 0, err
 	}
@@ -61,7 +64,11 @@ func includeChildren(pdf *PDF, o obj) error {
 	for _, child := range o.children() {
 		// finalize fonts
 		if f, ok := child.(*Font); ok {
-			calculateWidths(f)
+			if f.Composite {
+				calculateCIDWidths(f)
+			} else {
+				calculateWidths(f)
+			}
 
 			tmp := make(map[rune]struct{}, len(f.charset))
 			for key := range f.charset {
@@ -71,7 +78,17 @@ func includeChildren(pdf *PDF, o obj) error {
 				f.source.buf = f.srcb
 			} else {
 				f.Subsetter.Init(f.SFNT, f.srcb, f.srcPath)
-				b, err := f.Subsetter.Subset(tmp)
+				var b []byte
+				var err error
+				if gs, ok := f.Subsetter.(GlyphSubsetter); ok && f.Composite {
+					gset := make(map[uint16]struct{}, len(f.glyphs))
+					for gid := range f.glyphs {
+						gset[gid] = struct{}{}
+					}
+					b, err = gs.SubsetGlyphs(tmp, gset)
+				} else {
+					b, err = f.Subsetter.Subset(tmp)
+				}
 				if err != nil {
 					return err
 				} else {
@@ -0,0 +1,141 @@
+package gdf
+
+import (
+	"slices"
+
+	gtfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
+
+// A pairKerner applies the pair adjustment (PairPos) lookups of a font's GPOS 'kern' feature to pairs of glyphs.
+type pairKerner struct {
+	// the PairPos subtables of each 'kern' lookup, in lookup list order, of each script whose lookups are consulted, in
+	// order of preference
+	scripts [][][]tables.PairPos
+	upem    int
+	cache   map[[2]uint16]int
+}
+
+// newPairKerner returns a pairKerner for the 'kern' features of the default language systems of g's DFLT and latn scripts.
+// A pair of glyphs is kerned by the lookups of the DFLT script, or, if none of them applies to the pair, by those of the
+// latn script, since fonts often register only the kerning of some other script under DFLT. It returns nil if neither
+// script has such a feature.
+func newPairKerner(g *gtfont.GPOS, upem int) *pairKerner {
+	pk := &pairKerner{upem: upem, cache: make(map[[2]uint16]int)}
+	for _, tag := range []string{"DFLT", "latn"} {
+		if lookups := kernLookups(g, g.FindScript(ot.MustNewTag(tag))); len(lookups) != 0 {
+			pk.scripts = append(pk.scripts, lookups)
+		}
+	}
+	if len(pk.scripts) == 0 {
+		return nil
+	}
+	return pk
+}
+
+// kernLookups returns the PairPos subtables of each lookup of the 'kern' feature of the default language system of g's
+// script si.
+func kernLookups(g *gtfont.GPOS, si int) [][]tables.PairPos {
+	if si < 0 || g.Scripts[si].DefaultLangSys == nil {
+		return nil
+	}
+	var indices []uint16
+	for _, fi := range g.Scripts[si].DefaultLangSys.FeatureIndices {
+		if int(fi) < len(g.Features) && g.Features[fi].Tag == ot.MustNewTag("kern") {
+			indices = append(indices, g.Features[fi].LookupListIndices...)
+		}
+	}
+	slices.Sort(indices)
+	indices = slices.Compact(indices)
+
+	var lookups [][]tables.PairPos
+	for _, li := range indices {
+		if int(li) >= len(g.Lookups) {
+			continue
+		}
+		var subtables []tables.PairPos
+		for _, st := range g.Lookups[li].Subtables {
+			if pp, ok := st.(tables.PairPos); ok {
+				subtables = append(subtables, pp)
+			}
+		}
+		if len(subtables) != 0 {
+			lookups = append(lookups, subtables)
+		}
+	}
+	return lookups
+}
+
+// kern returns the adjustment, in font units, to the advance of g1 when it is followed by g2.
+func (pk *pairKerner) kern(g1, g2 uint16) int {
+	if k, ok := pk.cache[[2]uint16{g1, g2}]; ok {
+		return k
+	}
+	var sum int
+	for _, lookups := range pk.scripts {
+		var applied bool
+		for _, subtables := range lookups {
+			// within a lookup, only the first subtable that applies to the pair is used
+			for _, st := range subtables {
+				if adj, ok := pairAdjustment(st, tables.GlyphID(g1), tables.GlyphID(g2)); ok {
+					sum += adj
+					applied = true
+					break
+				}
+			}
+		}
+		if applied {
+			break
+		}
+	}
+	k := sum * ppem / pk.upem
+	pk.cache[[2]uint16{g1, g2}] = k
+	return k
+}
+
+// pairAdjustment returns the adjustment, in design units, to the distance between g1 and g2 that pp assigns to the pair,
+// and whether pp applies to the pair at all. The adjustment is the sum of the advance adjustment of g1 and the horizontal
+// placement adjustment of g2, which both move g2 relative to g1. Since unshaped text can only adjust the space between
+// a pair of glyphs, the horizontal placement of g1, the advance of g2, and all vertical adjustments are ignored.
+func pairAdjustment(pp tables.PairPos, g1, g2 tables.GlyphID) (int, bool) {
+	idx, ok := pp.Data.Cov().Index(g1)
+	if !ok {
+		return 0, false
+	}
+	switch d := pp.Data.(type) {
+	case tables.PairPosData1:
+		if idx >= len(d.PairSets) {
+			return 0, false
+		}
+		rec, ok := d.PairSets[idx].FindGlyph(g2)
+		if !ok {
+			return 0, false
+		}
+		return int(rec.ValueRecord1.XAdvance) + int(rec.ValueRecord2.XPlacement), true
+	case tables.PairPosData2:
+		c1, _ := d.ClassDef1.Class(g1)
+		c2, _ := d.ClassDef2.Class(g2)
+		// the record of a class that is out of range is not in the subtable; like HarfBuzz, treat the subtable as not
+		// applying to the pair, so that another lookup can. go-text does not export the class counts, but it requires
+		// them to equal the extents of the class definitions.
+		if int(c1) >= d.ClassDef1.Extent() || int(c2) >= d.ClassDef2.Extent() {
+			return 0, false
+		}
+		rec := d.Record(c1, c2)
+		return int(rec.ValueRecord1.XAdvance) + int(rec.ValueRecord2.XPlacement), true
+	}
+	return 0, false
+}
+
+// gposKerner returns f's pairKerner, or nil if f's GPOS table does not provide pair kerning.
+func (f *Font) gposKerner() *pairKerner {
+	if !f.kernInit {
+		f.kernInit = true
+		if hf, err := f.hbFont(); err == nil {
+			face := hf.Face()
+			f.kerner = newPairKerner(&face.GPOS, int(face.Upem()))
+		}
+	}
+	return f.kerner
+}
@@ -0,0 +1,202 @@
+// Package layout builds documents from sequences of Flowables, such as paragraphs, images, and tables, which are drawn to
+// the frames of pages that are added to a PDF as they are needed. Each page is laid out according to a PageTemplate, which
+// names the page's frames and can draw a header and footer on it.
+package layout
+
+import (
+	"fmt"
+
+	"github.com/cdillond/gdf"
+)
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	ErrTooLarge = Error("flowable does not fit in an empty frame")
+	ErrTemplate = Error("no page template has the given name")
+	ErrFrames   = Error("page template has no frames")
+	ErrFrame    = Error("page template has no frame with the given name")
+)
+
+// very slight differences due to floating point precision errors should be ok
+const epsilon = 1e-8
+
+// A Frame is a named area of a page to which Flowables are drawn.
+type Frame struct {
+	Name string
+	Area gdf.Rect
+}
+
+// A PageFunc draws to the ContentStream of a page, whose 1-based number in the Doc is n.
+type PageFunc func(c *gdf.ContentStream, n int) error
+
+// A PageTemplate describes the layout of a page: its size, the Frames to which Flowables are drawn, in order, and the
+// functions that draw its header and footer.
+type PageTemplate struct {
+	Name   string
+	Size   gdf.Rect
+	Frames []Frame
+	Header PageFunc // if not nil, called when the page is added to the PDF, before any Flowables are drawn to it
+	Footer PageFunc // if not nil, called when the page is finished, after all of its Flowables have been drawn
+}
+
+// A Doc draws Flowables to pages, which it adds to a PDF. The pages are laid out according to the Doc's PageTemplates;
+// the first page uses the first template, and each later page uses the template of the page before it, unless another
+// one is selected by a NextTemplate.
+type Doc struct {
+	PDF       *gdf.PDF
+	Templates []PageTemplate
+	tmpl      int       // the index of the template of the current page
+	next      int       // the index of the template of the next page
+	page      *gdf.Page // the current page; nil if no page has been started, or the last one has been finished
+	num       int       // the number of pages that have been started
+	frame     int       // the index of the current frame
+	used      float64   // the height of the current frame that has already been drawn to
+}
+
+// NewDoc returns a Doc that adds pages to pdf, which are laid out according to templates.
+func NewDoc(pdf *gdf.PDF, templates ...PageTemplate) *Doc {
+	return &Doc{PDF: pdf, Templates: templates}
+}
+
+// Build draws flowables to the frames of d's pages, starting new pages as needed, and then finishes the last page. A
+// Flowable that does not fit in the rest of a frame is continued in, or moved to, the next one.
+func (d *Doc) Build(flowables ...Flowable) error {
+	if err := d.draw(flowables); err != nil {
+		return err
+	}
+	return d.finishPage()
+}
+
+// draw draws flowables to d's frames.
+func (d *Doc) draw(flowables []Flowable) error {
+	for _, f := range flowables {
+		switch v := f.(type) {
+		case PageBreak:
+			if err := d.finishPage(); err != nil {
+				return err
+			}
+			continue
+		case FrameBreak:
+			if err := d.breakFrame(v.Name); err != nil {
+				return err
+			}
+			continue
+		case NextTemplate:
+			i, err := d.template(v.Name)
+			if err != nil {
+				return err
+			}
+			d.next = i
+			continue
+		}
+		for done := false; !done; {
+			if err := d.startPage(); err != nil {
+				return err
+			}
+			fr := d.Templates[d.tmpl].Frames[d.frame]
+			area := fr.Area
+			area.URY -= d.used
+			h, ok, err := f.Draw(d.page.ContentStream(), area)
+			if err != nil {
+				return err
+			}
+			d.used += h
+			done = ok
+			if done {
+				break
+			}
+			if h == 0 && d.used == 0 {
+				// the Flowables of a KeepTogether that does not fit in an empty frame are drawn separately
+				if k, isKeep := f.(KeepTogether); isKeep {
+					if err := d.draw(k); err != nil {
+						return err
+					}
+					break
+				}
+				return fmt.Errorf("%w: %q", ErrTooLarge, fr.Name)
+			}
+			if err := d.breakFrame(""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// template returns the index of d's template with the given name.
+func (d *Doc) template(name string) (int, error) {
+	for i, t := range d.Templates {
+		if t.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrTemplate, name)
+}
+
+// startPage adds a new page to d's PDF and draws its header, unless a page has already been started.
+func (d *Doc) startPage() error {
+	if d.page != nil {
+		return nil
+	}
+	if d.next >= len(d.Templates) || len(d.Templates[d.next].Frames) == 0 {
+		return ErrFrames
+	}
+	d.tmpl = d.next
+	t := d.Templates[d.tmpl]
+	p := gdf.NewPage(t.Size, gdf.Margins{})
+	d.PDF.AppendPage(&p)
+	d.page = &p
+	d.num++
+	d.frame, d.used = 0, 0
+	if t.Header != nil {
+		return t.Header(p.ContentStream(), d.num)
+	}
+	return nil
+}
+
+// finishPage draws the footer of the current page, if any. The next Flowable is drawn to a new page.
+func (d *Doc) finishPage() error {
+	if d.page == nil {
+		return nil
+	}
+	p := d.page
+	d.page = nil
+	if t := d.Templates[d.tmpl]; t.Footer != nil {
+		return t.Footer(p.ContentStream(), d.num)
+	}
+	return nil
+}
+
+// breakFrame moves d to the frame of the current page with the given name, if it follows the current frame, or to the
+// frame with that name on the next page. If name is empty, d moves to the next frame.
+func (d *Doc) breakFrame(name string) error {
+	if err := d.startPage(); err != nil {
+		return err
+	}
+	frames := d.Templates[d.tmpl].Frames
+	for i := d.frame + 1; i < len(frames); i++ {
+		if name == "" || frames[i].Name == name {
+			d.frame, d.used = i, 0
+			return nil
+		}
+	}
+	if err := d.finishPage(); err != nil {
+		return err
+	}
+	if err := d.startPage(); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	for i, fr := range d.Templates[d.tmpl].Frames {
+		if fr.Name == name {
+			d.frame = i
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrFrame, name)
+}
@@ -0,0 +1,256 @@
+package layout
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cdillond/gdf"
+	"github.com/cdillond/gdf/svg"
+	"github.com/cdillond/gdf/table"
+	"github.com/cdillond/gdf/text"
+)
+
+// A Flowable is an element of a document, such as a paragraph or an image, that a Doc draws to the frames of its pages,
+// one after another.
+type Flowable interface {
+	// Height returns the height, in points, of the undrawn part of the Flowable when it is drawn in a frame of the given width.
+	Height(width float64) (float64, error)
+	// Draw draws as much of the undrawn part of the Flowable as fits in area of c, and returns the height of the part of
+	// area that it used and whether the Flowable has been drawn completely. If none of it fits, Draw draws nothing and
+	// returns 0 and false.
+	Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error)
+}
+
+// A Paragraph is a Flowable text. Its lines are broken by a text.Controller at the width of the first frame in which it
+// is measured or drawn, and they can be divided among frames of that width or wider. A Paragraph keeps that line width
+// when it is continued in another frame; its lines are not broken again, so in a wider frame they do not fill the extra
+// width, and drawing them to a narrower frame fails with text.ErrWidth.
+type Paragraph struct {
+	newController func(lineWidth float64) (text.Controller, error)
+	tc            *text.Controller
+}
+
+// NewParagraph returns a Paragraph containing src, which is set in the fonts of f with the formatting specified by cfg.
+func NewParagraph(src text.FormatText, f text.FontFamily, cfg text.ControllerCfg) *Paragraph {
+	return &Paragraph{newController: func(lineWidth float64) (text.Controller, error) {
+		return text.NewController(src, lineWidth, f, cfg)
+	}}
+}
+
+// NewSpanParagraph returns a Paragraph containing the text of spans, which is set in the fonts of f with the formatting
+// specified by cfg.
+func NewSpanParagraph(spans []text.Span, f text.FontFamily, cfg text.ControllerCfg) *Paragraph {
+	return &Paragraph{newController: func(lineWidth float64) (text.Controller, error) {
+		return text.NewSpanController(spans, lineWidth, f, cfg)
+	}}
+}
+
+// controller returns p's Controller, which is created with the given line width if it does not exist yet.
+func (p *Paragraph) controller(width float64) (*text.Controller, error) {
+	if p.tc == nil {
+		tc, err := p.newController(width)
+		if err != nil {
+			return nil, err
+		}
+		p.tc = &tc
+	}
+	return p.tc, nil
+}
+
+func (p *Paragraph) Height(width float64) (float64, error) {
+	tc, err := p.controller(width)
+	if err != nil {
+		return 0, err
+	}
+	return tc.Height(), nil
+}
+
+func (p *Paragraph) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	tc, err := p.controller(area.Width())
+	if err != nil {
+		return 0, false, err
+	}
+	if tc.IsDone {
+		return 0, true, nil
+	}
+	h := tc.FitHeight(area.Height())
+	if h == 0 {
+		return 0, false, nil
+	}
+	if _, _, err = tc.DrawText(c, area); err != nil {
+		return 0, false, err
+	}
+	return h, tc.IsDone, nil
+}
+
+// A Spacer is a Flowable that leaves vertical space between the Flowables before and after it. A Spacer at the bottom
+// of a frame is truncated.
+type Spacer float64
+
+func (s Spacer) Height(width float64) (float64, error) { return float64(s), nil }
+
+func (s Spacer) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	return min(float64(s), area.Height()), true, nil
+}
+
+// An Image is a Flowable raster image, which is drawn at the left edge of its frame. If the image is wider than the frame,
+// it is scaled down to fit.
+type Image struct {
+	Img  *gdf.Image
+	W, H float64 // the size, in points, at which the image is drawn
+}
+
+func (im *Image) Height(width float64) (float64, error) {
+	_, h := fit(im.W, im.H, width)
+	return h, nil
+}
+
+func (im *Image) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	r, ok := place(im.W, im.H, area)
+	if !ok {
+		return 0, false, nil
+	}
+	c.DrawImageTo(r, im.Img)
+	return r.Height(), true, nil
+}
+
+// XContent is a Flowable form XObject, such as an SVG decoded by the svg package, which is drawn at the left edge of its
+// frame. If it is wider than the frame, it is scaled down to fit.
+type XContent struct {
+	X    *gdf.XContent
+	W, H float64 // the size, in points, at which X is drawn; if 0, the size of its BBox
+}
+
+// SVG decodes the SVG read from r and returns it as an XContent that is drawn width points wide, in proportion to its
+// bounding box.
+func SVG(r io.Reader, width float64) (*XContent, error) {
+	x, err := svg.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	if x.BBox.Width() <= 0 {
+		return nil, errors.New("SVG has an empty bounding box")
+	}
+	return &XContent{X: &x, W: width, H: width * x.BBox.Height() / x.BBox.Width()}, nil
+}
+
+// size returns the size at which x is drawn, if it is not scaled down.
+func (x *XContent) size() (float64, float64) {
+	if x.W == 0 || x.H == 0 {
+		return x.X.BBox.Width(), x.X.BBox.Height()
+	}
+	return x.W, x.H
+}
+
+func (x *XContent) Height(width float64) (float64, error) {
+	w, h := x.size()
+	_, h = fit(w, h, width)
+	return h, nil
+}
+
+func (x *XContent) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	w, h := x.size()
+	r, ok := place(w, h, area)
+	if !ok {
+		return 0, false, nil
+	}
+	c.DrawXContentTo(r, x.X)
+	return r.Height(), true, nil
+}
+
+// fit returns the size of a w×h object scaled down to fit the given width.
+func fit(w, h, width float64) (float64, float64) {
+	if w > width && w > 0 {
+		return width, h * width / w
+	}
+	return w, h
+}
+
+// place returns the rectangle at the top left of area in which a w×h object is drawn, and whether it fits in area.
+func place(w, h float64, area gdf.Rect) (gdf.Rect, bool) {
+	w, h = fit(w, h, area.Width())
+	return gdf.Rect{LLX: area.LLX, LLY: area.URY - h, URX: area.LLX + w, URY: area.URY}, h <= area.Height()+epsilon
+}
+
+// Table is a Flowable table.Table. Its rows can be divided among frames, at the top of each of which its header rows are
+// repeated.
+type Table struct {
+	*table.Table
+}
+
+func (t Table) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	h, more, err := t.Table.Draw(c, area)
+	switch {
+	case errors.Is(err, table.ErrHeight):
+		return 0, false, nil
+	case errors.Is(err, table.ErrEmpty):
+		return 0, true, nil
+	case err != nil:
+		return 0, false, err
+	}
+	return h, !more, nil
+}
+
+// A PageBreak ends the current page. The Flowables that follow it are drawn to the next page.
+type PageBreak struct{}
+
+func (PageBreak) Height(width float64) (float64, error) { return 0, nil }
+func (PageBreak) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	return 0, true, nil
+}
+
+// A FrameBreak ends the current frame. The Flowables that follow it are drawn to the frame of the current page with the
+// given Name, if it follows the current frame, or otherwise to the frame with that name on the next page. If Name is
+// empty, they are drawn to the next frame.
+type FrameBreak struct {
+	Name string
+}
+
+func (FrameBreak) Height(width float64) (float64, error) { return 0, nil }
+func (FrameBreak) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	return 0, true, nil
+}
+
+// NextTemplate selects the PageTemplate, by Name, of the pages that follow the current one.
+type NextTemplate struct {
+	Name string
+}
+
+func (NextTemplate) Height(width float64) (float64, error) { return 0, nil }
+func (NextTemplate) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	return 0, true, nil
+}
+
+// KeepTogether is a sequence of Flowables that are drawn in the same frame, unless they do not fit in an empty one.
+type KeepTogether []Flowable
+
+func (k KeepTogether) Height(width float64) (float64, error) {
+	var h float64
+	for _, f := range k {
+		fh, err := f.Height(width)
+		if err != nil {
+			return 0, err
+		}
+		h += fh
+	}
+	return h, nil
+}
+
+// Draw draws k's Flowables, as they are drawn by a Doc, if they all fit in area. Otherwise, it draws nothing.
+func (k KeepTogether) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	h, err := k.Height(area.Width())
+	if err != nil || h > area.Height()+epsilon {
+		return 0, false, err
+	}
+	var used float64
+	for _, f := range k {
+		r := area
+		r.URY -= used
+		fh, _, err := f.Draw(c, r)
+		if err != nil {
+			return 0, false, err
+		}
+		used += fh
+	}
+	return used, true, nil
+}
@@ -0,0 +1,109 @@
+package gdf
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// A Dest is a destination within a PDF: a page, and the point of the page, in default user space, that a PDF viewer
+// displays at the top left corner of its window when the destination is selected.
+type Dest struct {
+	Page *Page
+	X, Y float64
+}
+
+// ErrDest is returned when a PDF that contains a link or an outline item whose destination has no Page is written.
+var ErrDest = errors.New("destination has no page")
+
+// bytes returns the explicit destination array of d.
+func (d Dest) bytes() ([]byte, error) {
+	if d.Page == nil {
+		return nil, ErrDest
+	}
+	b := make([]byte, 0, 48)
+	b = append(b, '[')
+	b = append(b, iref(d.Page)...)
+	b = append(b, " /XYZ "...)
+	b = strconv.AppendFloat(b, d.X, 'f', -1, 64)
+	b = append(b, '\x20')
+	b = strconv.AppendFloat(b, d.Y, 'f', -1, 64)
+	return append(b, " null]"...), nil
+}
+
+// A LinkAnnot is an area of a page that takes the user to a destination in the PDF, or opens a URI, when it is clicked.
+// Link annotations are not drawn; any text or graphics that indicate the link must be drawn to the page's ContentStream.
+type LinkAnnot struct {
+	Dest  *Dest  // the destination of a link within the PDF; its Page must not be nil
+	URI   string // the URI opened by the link, if Dest is nil
+	Flags annotFlag
+
+	rect   Rect      // the annotation rectangle, in default user space
+	quads  []float64 // the QuadPoints of the areas that make up the link, if there are more than one
+	refnum int
+}
+
+func (l *LinkAnnot) mark(i int)      { l.refnum = i }
+func (l *LinkAnnot) id() int         { return l.refnum }
+func (l *LinkAnnot) children() []obj { return nil }
+func (l *LinkAnnot) encode(w io.Writer) (int, error) {
+	fields := []field{
+		{"/Type", "/Annot"},
+		{"/Subtype", "/Link"},
+		{"/Rect", l.rect},
+		{"/Border", "[0 0 0]"},
+		{"/F", uint32(l.Flags)},
+	}
+	if len(l.quads) != 0 {
+		fields = append(fields, field{"/QuadPoints", l.quads})
+	}
+	if l.Dest != nil {
+		dest, err := l.Dest.bytes()
+		if err != nil {
+			return 0, err
+		}
+		fields = append(fields, field{"/Dest", dest})
+	} else if l.URI != "" {
+		fields = append(fields, field{"/A", subdict(64, []field{{"/S", "/URI"}, {"/URI", htxt([]byte(l.URI))}})})
+	}
+	return w.Write(dict(256, fields))
+}
+
+// Link adds l to the page to which c belongs, as a link from the area r, which is specified in the current user space.
+// Links are only added to the ContentStreams of pages.
+func (c *ContentStream) Link(l *LinkAnnot, r Rect) {
+	l.rect, l.quads = c.bbox(r), nil
+	c.resources.Links = append(c.resources.Links, l)
+}
+
+// LinkAreas is like Link, but the link consists of each of the areas rs, e.g., the parts of a phrase that is broken across
+// lines. Its rectangle encloses all of them, and, if there is more than one, they are recorded as its QuadPoints, so that
+// viewers that support them only respond to clicks within the areas. LinkAreas does nothing if rs is empty.
+func (c *ContentStream) LinkAreas(l *LinkAnnot, rs ...Rect) {
+	if len(rs) == 0 {
+		return
+	}
+	l.rect, l.quads = c.bbox(rs[0]), nil
+	if len(rs) > 1 {
+		for _, r := range rs {
+			ll, ul, lr, ur := TransformRect(r, c.GS.Matrix)
+			// the vertices of each quadrilateral are given in counterclockwise order
+			l.quads = append(l.quads, ll.X, ll.Y, lr.X, lr.Y, ur.X, ur.Y, ul.X, ul.Y)
+			b := c.bbox(r)
+			l.rect = Rect{LLX: math.Min(l.rect.LLX, b.LLX), LLY: math.Min(l.rect.LLY, b.LLY), URX: math.Max(l.rect.URX, b.URX), URY: math.Max(l.rect.URY, b.URY)}
+		}
+	}
+	c.resources.Links = append(c.resources.Links, l)
+}
+
+// bbox returns the bounding box, in default user space, of r, which is specified in c's current user space.
+func (c *ContentStream) bbox(r Rect) Rect {
+	ll, ul, lr, ur := TransformRect(r, c.GS.Matrix)
+	return Rect{
+		LLX: math.Min(math.Min(ll.X, ul.X), math.Min(lr.X, ur.X)),
+		LLY: math.Min(math.Min(ll.Y, ul.Y), math.Min(lr.Y, ur.Y)),
+		URX: math.Max(math.Max(ll.X, ul.X), math.Max(lr.X, ur.X)),
+		URY: math.Max(math.Max(ll.Y, ul.Y), math.Max(lr.Y, ur.Y)),
+	}
+}
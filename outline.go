@@ -0,0 +1,121 @@
+package gdf
+
+import (
+	"io"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// An OutlineItem is an entry of a PDF's document outline, which PDF viewers display as a tree of bookmarks. Selecting an
+// item takes the user to its Dest, whose Page must not be nil.
+type OutlineItem struct {
+	Title    string
+	Dest     Dest
+	Open     bool // whether the item's Children are shown when the PDF is opened
+	Children []*OutlineItem
+
+	parent     obj
+	prev, next *OutlineItem
+	refnum     int
+}
+
+// the root of the document outline
+type outlines struct {
+	items  []*OutlineItem
+	refnum int
+}
+
+// AddOutlineItems appends items to the top level of p's document outline. To show the outline when the PDF is opened,
+// set p's PageMode to OutlinesMode.
+func (p *PDF) AddOutlineItems(items ...*OutlineItem) {
+	if p.catalog.outlines == nil {
+		p.catalog.outlines = new(outlines)
+	}
+	p.catalog.outlines.items = append(p.catalog.outlines.items, items...)
+}
+
+// link sets the parent and siblings of each of items.
+func link(parent obj, items []*OutlineItem) []obj {
+	out := make([]obj, len(items))
+	for i, it := range items {
+		it.parent, it.prev, it.next = parent, nil, nil
+		if i > 0 {
+			it.prev = items[i-1]
+		}
+		if i < len(items)-1 {
+			it.next = items[i+1]
+		}
+		out[i] = it
+	}
+	return out
+}
+
+// visible returns the number of items, and of their descendants, that are shown when the outline is opened.
+func visible(items []*OutlineItem) int {
+	n := len(items)
+	for _, it := range items {
+		if it.Open {
+			n += visible(it.Children)
+		}
+	}
+	return n
+}
+
+func (o *outlines) mark(i int)      { o.refnum = i }
+func (o *outlines) id() int         { return o.refnum }
+func (o *outlines) children() []obj { return link(o, o.items) }
+func (o *outlines) encode(w io.Writer) (int, error) {
+	return w.Write(dict(128, []field{
+		{"/Type", "/Outlines"},
+		{"/First", iref(o.items[0])},
+		{"/Last", iref(o.items[len(o.items)-1])},
+		{"/Count", visible(o.items)},
+	}))
+}
+
+func (it *OutlineItem) mark(i int)      { it.refnum = i }
+func (it *OutlineItem) id() int         { return it.refnum }
+func (it *OutlineItem) children() []obj { return link(it, it.Children) }
+func (it *OutlineItem) encode(w io.Writer) (int, error) {
+	dest, err := it.Dest.bytes()
+	if err != nil {
+		return 0, err
+	}
+	fields := []field{
+		{"/Title", textstring(it.Title)},
+		{"/Parent", iref(it.parent)},
+		{"/Dest", dest},
+	}
+	if it.prev != nil {
+		fields = append(fields, field{"/Prev", iref(it.prev)})
+	}
+	if it.next != nil {
+		fields = append(fields, field{"/Next", iref(it.next)})
+	}
+	if len(it.Children) > 0 {
+		// a closed item's count is negative
+		n := visible(it.Children)
+		if !it.Open {
+			n = -n
+		}
+		fields = append(fields,
+			field{"/First", iref(it.Children[0])},
+			field{"/Last", iref(it.Children[len(it.Children)-1])},
+			field{"/Count", n},
+		)
+	}
+	return w.Write(dict(256, fields))
+}
+
+// textstring returns s as a hex-encoded, UTF-16BE PDF text string. Unlike utf16BEstring, it does not need to escape
+// any bytes of the encoded text.
+func textstring(s string) []byte {
+	if utf16 == nil {
+		utf16 = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder()
+	}
+	t, err := utf16.Bytes([]byte(s))
+	if err != nil {
+		return []byte("<>")
+	}
+	return htxt(append([]byte{0xFE, 0xFF}, t...))
+}
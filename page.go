@@ -3,6 +3,7 @@ package gdf
 import (
 	"errors"
 	"io"
+	"slices"
 )
 
 type Page struct {
@@ -33,6 +34,7 @@ type resourceDict struct {
 
 	Widgets    []*Widget
 	TextAnnots []*TextAnnot
+	Links      []*LinkAnnot
 
 	/*
 		TODO:
@@ -111,6 +113,7 @@ func (p *PDF) InsertPage(page *Page, i int) error {
 	if i < 0 || i > len(p.catalog.pages.P) {
 		return errors.New("out of bounds")
 	}
+	page.parent = p.catalog.pages
 	if i == len(p.catalog.pages.P) {
 		p.catalog.pages.P = append(p.catalog.pages.P, page)
 		return nil
@@ -128,10 +131,16 @@ func (p *PDF) ReplacePage(page *Page, i int) error {
 	if i < 0 || i >= len(p.catalog.pages.P) {
 		return errors.New("out of bounds")
 	}
+	page.parent = p.catalog.pages
 	p.catalog.pages.P[i] = page
 	return nil
 }
 
+// PageIndex returns the index of page in the PDF's internal page structure, or -1 if the PDF does not contain it.
+func (p *PDF) PageIndex(page *Page) int {
+	return slices.Index(p.catalog.pages.P, page)
+}
+
 func (p *Page) mark(i int) { p.refnum = i }
 func (p *Page) id() int    { return p.refnum }
 func (p *Page) children() []obj {
@@ -160,20 +169,26 @@ func (p *Page) children() []obj {
 	for i := range p.c.resources.Widgets {
 		out = append(out, p.c.resources.Widgets[i])
 	}
+	for i := range p.c.resources.Links {
+		out = append(out, p.c.resources.Links[i])
+	}
 	return append(out, p.c)
 }
 
 func (p *Page) encode(w io.Writer) (int, error) {
 	var fields []field
 
-	if len(p.c.resources.Widgets) > 0 {
-		a := make([]string, 0, len(p.c.resources.Widgets)+len(p.c.resources.TextAnnots))
+	if len(p.c.resources.Widgets)+len(p.c.resources.TextAnnots)+len(p.c.resources.Links) > 0 {
+		a := make([]string, 0, len(p.c.resources.Widgets)+len(p.c.resources.TextAnnots)+len(p.c.resources.Links))
 		for _, an := range p.c.resources.TextAnnots {
 			a = append(a, iref(an))
 		}
 		for _, an := range p.c.resources.Widgets {
 			a = append(a, iref(an))
 		}
+		for _, an := range p.c.resources.Links {
+			a = append(a, iref(an))
+		}
 		fields = append(fields, field{
 			"/Annots", a,
 		})
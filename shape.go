@@ -0,0 +1,223 @@
+package gdf
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+
+	gtfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/unicodedata"
+)
+
+// A Feature is an OpenType layout feature setting. Tag is the feature's tag, e.g., "liga", "smcp", "tnum", "onum", or "ss01".
+// A Value of 0 disables the feature, and a Value of 1 enables it. For features that select one of several alternates, such as
+// "salt" or "cv01", Value is the 1-based index of the alternate.
+type Feature struct {
+	Tag   string
+	Value uint32
+}
+
+// A Glyph is a positioned glyph, usually produced by shaping text with a Font. Advances and offsets are expressed in font units.
+type Glyph struct {
+	ID      uint16 // the glyph's index in the font
+	Cluster int    // the index, in the shaped text, of the first rune of the cluster to which the glyph belongs
+	Runes   []rune // the text represented by the glyph; a cluster's text is assigned only to its first glyph
+	Adv     int    // the horizontal advance of the glyph, including any adjustments made by the shaper
+	XOff    int    // the horizontal offset of the glyph from its nominal position
+	YOff    int    // the vertical offset of the glyph from its nominal position
+}
+
+// Shape applies the glyph substitution (GSUB) and positioning (GPOS) rules of f to t, according to the default features of t's
+// script and the supplied feature settings, and returns the resulting glyphs in the order in which they should be drawn.
+// The script, language, and direction of t are inferred from its contents. Glyphs produced by shaping can be drawn with
+// ContentStream.ShowGlyphs, but any glyphs that are not mapped to a Windows-1252 character (e.g., ligatures, alternates, and
+// the glyphs of most non-Latin scripts) can only be drawn if f is Composite.
+func (f *Font) Shape(t []rune, features []Feature) ([]Glyph, error) {
+	return f.shape(t, 0, features)
+}
+
+// ShapeDirection is like Shape, but t is shaped in the given direction instead of the direction inferred from its contents.
+// If rtl is true, t is shaped from right to left, and its mirrored characters, such as parentheses and brackets, are drawn
+// with the glyphs of their mirror images. In either case, the glyphs are returned in the order in which they should be
+// drawn, from left to right. Text that mixes left-to-right and right-to-left characters should be divided into runs of a single
+// direction, according to the Unicode Bidirectional Algorithm, and each run should be shaped separately.
+func (f *Font) ShapeDirection(t []rune, rtl bool, features []Feature) ([]Glyph, error) {
+	if rtl {
+		return f.shape(t, harfbuzz.RightToLeft, features)
+	}
+	return f.shape(t, harfbuzz.LeftToRight, features)
+}
+
+// shape shapes t in the direction dir, or, if dir is 0, in the direction inferred from t.
+func (f *Font) shape(t []rune, dir harfbuzz.Direction, features []Feature) ([]Glyph, error) {
+	hf, err := f.hbFont()
+	if err != nil {
+		return nil, err
+	}
+	feats := make([]harfbuzz.Feature, len(features))
+	for i, ft := range features {
+		if len(ft.Tag) == 0 || len(ft.Tag) > 4 {
+			return nil, fmt.Errorf("invalid feature tag %q", ft.Tag)
+		}
+		tag := []byte("    ")
+		copy(tag, ft.Tag)
+		feats[i] = harfbuzz.Feature{
+			Tag:   ot.NewTag(tag[0], tag[1], tag[2], tag[3]),
+			Value: ft.Value,
+			Start: harfbuzz.FeatureGlobalStart,
+			End:   harfbuzz.FeatureGlobalEnd,
+		}
+	}
+
+	buf := harfbuzz.NewBuffer()
+	buf.AddRunes(t, 0, -1)
+	buf.Props.Direction = dir
+	buf.GuessSegmentProperties()
+	buf.Shape(hf, feats)
+
+	// each cluster's text extends to the start of the next cluster
+	starts := make([]int, len(buf.Info))
+	for i := range buf.Info {
+		starts[i] = buf.Info[i].Cluster
+	}
+	slices.Sort(starts)
+	starts = slices.Compact(starts)
+
+	out := make([]Glyph, len(buf.Info))
+	seen := make(map[int]bool, len(starts))
+	for i, info := range buf.Info {
+		out[i] = Glyph{
+			ID:      uint16(info.Glyph),
+			Cluster: info.Cluster,
+			Adv:     int(buf.Pos[i].XAdvance),
+			XOff:    int(buf.Pos[i].XOffset),
+			YOff:    int(buf.Pos[i].YOffset),
+		}
+		if !seen[info.Cluster] {
+			seen[info.Cluster] = true
+			end := len(t)
+			if j, _ := slices.BinarySearch(starts, info.Cluster); j+1 < len(starts) {
+				end = starts[j+1]
+			}
+			out[i].Runes = slices.Clone(t[info.Cluster:end])
+		}
+	}
+	return out, nil
+}
+
+// RuneGlyph returns the unshaped Glyph to which f's character map maps r.
+func (f *Font) RuneGlyph(r rune) Glyph {
+	gid, _ := f.SFNT.GlyphIndex(f.buf, r)
+	return Glyph{ID: uint16(gid), Runes: []rune{r}, Adv: f.GlyphAdvance(r)}
+}
+
+// hbFont returns the HarfBuzz font used to shape text set in f, scaled so that positions are expressed in font units.
+func (f *Font) hbFont() (*harfbuzz.Font, error) {
+	if f.shaper == nil {
+		face, err := gtfont.ParseTTF(bytes.NewReader(f.srcb))
+		if err != nil {
+			return nil, err
+		}
+		f.shaper = harfbuzz.NewFont(face)
+		f.shaper.XScale, f.shaper.YScale = ppem, ppem
+	}
+	return f.shaper, nil
+}
+
+// encodeGlyph returns the character codes used to draw g in f, along with their combined advance in font units and the number
+// of codes equal to 32, to which word spacing applies. If f is not Composite, g is represented by the encodable runes of its text.
+func (f *Font) encodeGlyph(g Glyph) (code []byte, adv int, spaces int) {
+	for _, r := range g.Runes {
+		f.GlyphAdvance(r) // ensures r is included in the cutset passed to f's Subsetter
+	}
+	if f.Composite {
+		return f.useGlyph(g.ID, g.Runes), f.glyphIDAdvance(g.ID), 0
+	}
+	runes := g.Runes
+	if len(runes) == 1 {
+		// the glyph may be the mirror image of its rune, drawn in right-to-left text
+		if m, ok := unicodedata.LookupMirrorChar(runes[0]); ok {
+			if gid, _ := f.SFNT.GlyphIndex(f.buf, m); uint16(gid) == g.ID && g.ID != 0 {
+				runes = []rune{m}
+			}
+		}
+	}
+	for _, r := range runes {
+		b, err := f.enc.Bytes([]byte(string(r)))
+		if err != nil || len(b) == 0 {
+			continue
+		}
+		code = append(code, b...)
+		adv += f.GlyphAdvance(r)
+		if b[0] == '\x20' {
+			spaces++
+		}
+	}
+	return code, adv, spaces
+}
+
+// ShowGlyphs writes g to c and advances the text matrix by the total advance of g; TJ. The glyphs should have been produced by
+// c's current font. Each glyph is drawn at its nominal position, adjusted by its offsets, and word spacing is applied to
+// glyphs that represent the space character. If c's current font is not Composite, each glyph is drawn as the text that it
+// represents, so ligatures are replaced by their component characters and alternates by their default forms.
+func (c *ContentStream) ShowGlyphs(g []Glyph) {
+	ws := PtToFU(c.WordSpace, c.FontSize)
+	var pen, cur float64 // the nominal and actual horizontal positions in font units
+	var rise int
+	tmp := make([]byte, 0, 512)
+	flush := func() {
+		if len(tmp) != 0 {
+			c.buf = append(c.buf, htxt(tmp)...)
+			tmp = tmp[:0]
+		}
+	}
+	c.buf = append(c.buf, '[')
+	for _, gl := range g {
+		if gl.YOff != rise {
+			flush()
+			c.buf = append(c.buf, "] TJ\n"...)
+			c.buf = cmdf(c.buf, op_Ts, c.Rise+FUToPt(float64(gl.YOff), c.FontSize))
+			c.buf = append(c.buf, '[')
+			rise = gl.YOff
+		}
+		if d := pen + float64(gl.XOff) - cur; d != 0 {
+			flush()
+			c.buf = strconv.AppendFloat(c.buf, -d, 'f', -1, 64)
+			cur += d
+		}
+		code, adv, spaces := c.Font.encodeGlyph(gl)
+		tmp = append(tmp, code...)
+		cur += float64(adv) + ws*float64(spaces)
+		pen += float64(gl.Adv)
+		if len(gl.Runes) == 1 && gl.Runes[0] == '\x20' {
+			pen += ws
+		}
+	}
+	if d := pen - cur; d != 0 {
+		flush()
+		c.buf = strconv.AppendFloat(c.buf, -d, 'f', -1, 64)
+	}
+	flush()
+	c.buf = append(c.buf, "] TJ\n"...)
+	if rise != 0 {
+		c.buf = cmdf(c.buf, op_Ts, c.Rise)
+	}
+	ext := FUToPt(pen, c.FontSize) * c.HScale / 100
+	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, ext, 0})
+}
+
+// runeGlyphs returns the unshaped glyphs of t in f, with the kerning in kerns added to their advances.
+func (f *Font) runeGlyphs(t []rune, kerns []int) []Glyph {
+	out := make([]Glyph, len(t))
+	for i, r := range t {
+		out[i] = f.RuneGlyph(r)
+		out[i].Cluster = i
+		if kerns != nil {
+			out[i].Adv += kerns[i]
+		}
+	}
+	return out
+}
@@ -14,6 +14,12 @@ type BasicSubsetter struct {
 func (b *BasicSubsetter) Subset(cutset map[rune]struct{}) ([]byte, error) {
 	return TTFSubset(b.sFNT, b.src, cutset)
 }
+
+// SubsetGlyphs subsets the font so that it retains the glyphs for the runes in cutset as well as the glyphs in gset.
+func (b *BasicSubsetter) SubsetGlyphs(cutset map[rune]struct{}, gset map[uint16]struct{}) ([]byte, error) {
+	return TTFSubsetGlyphs(b.sFNT, b.src, cutset, gset)
+}
+
 func (b *BasicSubsetter) Init(SFNT *sfnt.Font, src []byte, _ string) {
 	b.sFNT = SFNT
 	b.src = src
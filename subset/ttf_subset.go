@@ -42,6 +42,13 @@ var pdfTables = [...]TableTag{
 // contains cmap, glyf, head, hhea, hmtx, loca, and maxp tables. The glyph indices are not affected. src should be a copy of the source
 // bytes for f.
 func TTFSubset(f *sfnt.Font, src []byte, cutset map[rune]struct{}) ([]byte, error) {
+	return TTFSubsetGlyphs(f, src, cutset, nil)
+}
+
+// TTFSubsetGlyphs is like TTFSubset, but it also retains the outlines of the glyphs in gset, which need not be reachable
+// from any rune in cutset. This makes it suitable for fonts whose glyphs are selected by a shaper, such as ligatures
+// and contextual alternates.
+func TTFSubsetGlyphs(f *sfnt.Font, src []byte, cutset map[rune]struct{}, gset map[uint16]struct{}) ([]byte, error) {
 	sbuf := new(sfnt.Buffer)
 	glyphs := make([]uint32, 0, 256)
 	glyphs = append(glyphs, 0) // must include .notdef
@@ -52,8 +59,19 @@ func TTFSubset(f *sfnt.Font, src []byte, cutset map[rune]struct{}) ([]byte, erro
 		if gid == 0 {
 			continue
 		}
-		glyphs = append(glyphs, uint32(gid))
-		glyphset[uint32(gid)] = struct{}{}
+		if _, seen := glyphset[uint32(gid)]; !seen {
+			glyphs = append(glyphs, uint32(gid))
+			glyphset[uint32(gid)] = struct{}{}
+		}
+	}
+	for gid := range gset {
+		if int(gid) >= f.NumGlyphs() {
+			return nil, fmt.Errorf("invalid glyph ID %d", gid)
+		}
+		if _, seen := glyphset[uint32(gid)]; !seen {
+			glyphs = append(glyphs, uint32(gid))
+			glyphset[uint32(gid)] = struct{}{}
+		}
 	}
 	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
 
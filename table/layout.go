@@ -0,0 +1,223 @@
+package table
+
+import (
+	"fmt"
+
+	"github.com/cdillond/gdf/text"
+)
+
+// layout places t's cells in its grid, and determines the widths of its columns, which must fit in the given width, and
+// the heights of its rows.
+func (t *Table) layout(width float64) error {
+	if err := t.place(); err != nil {
+		return err
+	}
+	cols, err := t.colWidths(width)
+	if err != nil {
+		return err
+	}
+	t.cols = cols
+	t.heights = make([]float64, len(t.Rows))
+	for i, row := range t.Rows {
+		t.heights[i] = row.MinHeight
+	}
+	padW := t.Padding.Left + t.Padding.Right
+	for i := range t.cells {
+		cl := &t.cells[i]
+		if len(cl.Text) == 0 {
+			continue
+		}
+		var w float64
+		for k := cl.col; k < cl.col+cl.colSpan; k++ {
+			w += cols[k]
+		}
+		tc, err := t.controller(cl.Cell, w-padW)
+		if err != nil {
+			return err
+		}
+		cl.tc = &tc
+	}
+	// cells that span a single row determine its height, and any additional height needed by cells that span several
+	// rows is added to the last of them
+	for _, cl := range t.cells {
+		if cl.rowSpan == 1 {
+			t.heights[cl.row] = max(t.heights[cl.row], t.height(cl))
+		}
+	}
+	for _, cl := range t.cells {
+		if cl.rowSpan > 1 {
+			last := cl.row + cl.rowSpan - 1
+			if need := t.height(cl) - t.rowsHeight(cl.row, last+1); need > 0 {
+				t.heights[last] += need
+			}
+		}
+	}
+	return nil
+}
+
+// controller returns a Controller that sets the text of c in lines of the given width.
+func (t *Table) controller(c Cell, lineWidth float64) (text.Controller, error) {
+	if lineWidth <= 0 {
+		return text.Controller{}, ErrWidth
+	}
+	cfg := t.Cfg
+	if c.Cfg != nil {
+		cfg = *c.Cfg
+	}
+	return text.NewSpanController(c.Text, lineWidth, t.Family, cfg)
+}
+
+// height returns the height of cl, including its padding.
+func (t *Table) height(cl cell) float64 {
+	h := t.Padding.Top + t.Padding.Bottom
+	if cl.tc != nil {
+		h += cl.tc.Height()
+	}
+	return h
+}
+
+// place assigns each of t's cells to the rows and columns it occupies.
+func (t *Table) place() error {
+	t.cells = t.cells[:0]
+	// taken[i][j] indicates whether column j of row i is occupied by a cell that spans it from a row above
+	taken := make([][]bool, len(t.Rows))
+	for i := range taken {
+		taken[i] = make([]bool, len(t.Columns))
+	}
+	for i, row := range t.Rows {
+		j := 0
+		for _, c := range row.Cells {
+			for j < len(t.Columns) && taken[i][j] {
+				j++
+			}
+			cl := cell{Cell: c, row: i, col: j, rowSpan: max(c.RowSpan, 1), colSpan: max(c.ColSpan, 1)}
+			cl.rowSpan = min(cl.rowSpan, len(t.Rows)-i)
+			if j+cl.colSpan > len(t.Columns) {
+				return fmt.Errorf("%w: row %d", ErrColumns, i)
+			}
+			for r := i; r < i+cl.rowSpan; r++ {
+				for k := j; k < j+cl.colSpan; k++ {
+					taken[r][k] = true
+				}
+			}
+			t.cells = append(t.cells, cl)
+			j += cl.colSpan
+		}
+	}
+	return nil
+}
+
+// colWidths returns the widths of t's columns when the table is laid out in the given width. Fixed columns take their
+// widths, and Auto and Proportional columns are at least as wide as the widest unbreakable text of their cells. Any width
+// that remains is used to widen the Auto columns, up to the natural widths of their cells' text, and then shared among the
+// Proportional columns.
+func (t *Table) colWidths(width float64) ([]float64, error) {
+	n := len(t.Columns)
+	minW, maxW := make([]float64, n), make([]float64, n)
+	for j, col := range t.Columns {
+		if col.Kind == Fixed {
+			minW[j], maxW[j] = col.Width, col.Width
+		}
+	}
+	padW := t.Padding.Left + t.Padding.Right
+	// cells that span a single column are measured first, so that the columns spanned by wider cells are only widened
+	// as much as necessary
+	for _, single := range []bool{true, false} {
+		for _, cl := range t.cells {
+			if len(cl.Text) == 0 || (cl.colSpan == 1) != single {
+				continue
+			}
+			var grow []int // the columns spanned by cl that can be widened
+			for k := cl.col; k < cl.col+cl.colSpan; k++ {
+				if t.Columns[k].Kind != Fixed {
+					grow = append(grow, k)
+				}
+			}
+			if len(grow) == 0 {
+				continue
+			}
+			tc, err := t.controller(cl.Cell, width-padW)
+			if err != nil {
+				return nil, err
+			}
+			widen(minW, grow, cl.col, cl.colSpan, tc.MinWidth()+padW)
+			widen(maxW, grow, cl.col, cl.colSpan, max(tc.Width(), tc.MinWidth())+padW)
+		}
+	}
+
+	widths := make([]float64, n)
+	rem := width
+	var stretch float64 // the amount by which the Auto columns can be widened
+	var weights float64 // the combined weights of the Proportional columns
+	for j, col := range t.Columns {
+		maxW[j] = max(maxW[j], minW[j])
+		widths[j] = minW[j]
+		rem -= minW[j]
+		switch col.Kind {
+		case Auto:
+			stretch += maxW[j] - minW[j]
+		case Proportional:
+			weights += max(col.Width, 0)
+		}
+	}
+	if rem < -epsilon {
+		return nil, ErrWidth
+	}
+	if stretch > 0 {
+		f := min(rem/stretch, 1)
+		for j, col := range t.Columns {
+			if col.Kind == Auto {
+				widths[j] += f * (maxW[j] - minW[j])
+			}
+		}
+		rem -= f * stretch
+	}
+	if weights > 0 && rem > 0 {
+		shareProportional(t.Columns, widths, rem, weights)
+	}
+	return widths, nil
+}
+
+// widen increases the widths of the columns in grow, which are among the span columns beginning with column start, so
+// that the span is at least w wide.
+func widen(widths []float64, grow []int, start, span int, w float64) {
+	for k := start; k < start+span; k++ {
+		w -= widths[k]
+	}
+	if w <= 0 {
+		return
+	}
+	for _, k := range grow {
+		widths[k] += w / float64(len(grow))
+	}
+}
+
+// shareProportional adds rem to the widths of the Proportional columns of cols so that they are, as nearly as their
+// minimum widths allow, in proportion to their weights, whose sum is weights.
+func shareProportional(cols []Column, widths []float64, rem, weights float64) {
+	// the columns that are already wider than their share keep their widths, and the others share what is left
+	fixed := make([]bool, len(cols))
+	for {
+		total, w := rem, weights
+		for j, col := range cols {
+			if col.Kind == Proportional && !fixed[j] {
+				total += widths[j]
+			}
+		}
+		changed := false
+		for j, col := range cols {
+			if col.Kind == Proportional && !fixed[j] && total*max(col.Width, 0)/w < widths[j] {
+				fixed[j], changed = true, true
+				weights -= max(col.Width, 0)
+			}
+		}
+		if !changed || weights <= 0 {
+			for j, col := range cols {
+				if col.Kind == Proportional && !fixed[j] && w > 0 {
+					widths[j] = total * max(col.Width, 0) / w
+				}
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,408 @@
+// Package table lays out and draws tables whose cells contain text set by text.Controllers. Column widths can be fixed,
+// proportional, or sized to fit the cells' text; cells can span several rows and columns; and tables that are too tall for
+// a single area can be drawn across several, e.g., on successive pages, with their header rows repeated at the top of each.
+package table
+
+import (
+	"github.com/cdillond/gdf"
+	"github.com/cdillond/gdf/text"
+)
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	ErrWidth   = Error("table columns cannot fit in the width of the target area")
+	ErrHeight  = Error("target area must be tall enough to contain the table's header rows and part of its next row")
+	ErrColumns = Error("row contains more cells than the table has columns")
+	ErrEmpty   = Error("table has no rows left to draw")
+)
+
+type WidthKind uint
+
+const (
+	Auto         WidthKind = iota // the column is sized to fit the text of its cells
+	Fixed                         // the column is Width points wide
+	Proportional                  // the column takes a share of the width left over by the other columns in proportion to its Width
+)
+
+// A Column specifies how the width of a table column is determined.
+type Column struct {
+	Kind  WidthKind
+	Width float64
+}
+
+type VAlignment uint
+
+const (
+	Top VAlignment = iota
+	Middle
+	Bottom
+)
+
+// A Border specifies the lines drawn around each of a table's cells.
+type Border struct {
+	Width float64   // line width in points; if 0, no borders are drawn
+	Color gdf.Color // stroking color; if nil, black
+}
+
+// A Cell is a table cell. Its text is set by a text.Controller whose line width is the width of the cell's columns, less
+// the table's Padding.
+type Cell struct {
+	Text    []text.Span
+	Cfg     *text.ControllerCfg // if nil, the Table's Cfg
+	ColSpan int                 // the number of columns the cell spans; if 0, 1
+	RowSpan int                 // the number of rows the cell spans; if 0, 1
+	VAlign  VAlignment          // the vertical alignment of the cell's text
+	Fill    gdf.Color           // background color; if nil, the Row's Fill
+}
+
+// NewCell returns a Cell containing s, which is set in the Table's default font and style.
+func NewCell(s string) Cell {
+	return Cell{Text: []text.Span{{Text: s}}}
+}
+
+// A Row is a table row. Its Cells occupy the columns that are not taken by cells that span it from the rows above, from
+// left to right.
+type Row struct {
+	Cells     []Cell
+	Fill      gdf.Color // background color of the row's cells; if nil, none
+	MinHeight float64   // the minimum height of the row, in points
+}
+
+// A Table is a grid of cells that can be drawn to one or more areas of ContentStreams. It is laid out when it is first
+// drawn, at the width of the area to which it is drawn.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+	// HeaderRows is the number of Rows at the start of Rows that are repeated at the top of each area to which the
+	// table is drawn. If all of the Rows are header rows, they are drawn once, like the rows of a table without header rows.
+	HeaderRows int
+	Family     text.FontFamily    // the fonts used to set the text of the cells
+	Cfg        text.ControllerCfg // the default ControllerCfg of the cells, e.g., one returned by text.NewControllerCfg
+	Padding    gdf.Margins        // the space between the edges of each cell and its text
+	Border     Border
+	// SplitRows indicates whether a row that does not fit in the rest of an area can be split across areas, rather than
+	// moved to the next one. Rows that do not fit in an otherwise empty area are always split. Rows joined by cells that
+	// span them are never split.
+	SplitRows bool
+
+	cols    []float64 // column widths
+	cells   []cell    // cells in order of their starting rows and columns
+	heights []float64 // row heights
+	next    int       // index of the next row that has not been completely drawn
+	split   bool      // whether the next row has been partially drawn, in which case its height is that of its remainder
+}
+
+// a Cell that has been placed in the table's grid
+type cell struct {
+	Cell
+	row, col         int
+	rowSpan, colSpan int
+	tc               *text.Controller // nil if the cell has no text
+}
+
+// Draw draws as many of t's remaining rows as fit in area of c, beginning with t's header rows, and returns the height of
+// the part of area that was drawn to. The returned bool indicates whether t has more rows to draw; if it is true, Draw
+// can be called again to draw them, usually to an area of another page; once it is false, Draw returns ErrEmpty. Changes
+// made to c's colors, line width, font, and text state by Draw continue to affect c after Draw returns.
+func (t *Table) Draw(c *gdf.ContentStream, area gdf.Rect) (float64, bool, error) {
+	if t.cols == nil {
+		if err := t.layout(area.Width()); err != nil {
+			return 0, false, err
+		}
+	}
+	if t.Width()-area.Width() > epsilon {
+		return 0, false, ErrWidth
+	}
+	// t.next is only 0 before t is first drawn, so a table of only header rows is drawn once
+	if t.next >= len(t.Rows) {
+		return 0, false, ErrEmpty
+	}
+	headEnd := t.headEnd()
+	if t.next < headEnd {
+		t.next = headEnd
+	}
+	headHeight := t.rowsHeight(0, headEnd)
+	avail := area.Height() - headHeight
+	if t.next < len(t.Rows) {
+		if end := t.bandEnd(t.next); t.rowsHeight(t.next, end) > avail+epsilon && (end > t.next+1 || t.splitHeight(avail) == 0) {
+			return 0, false, ErrHeight
+		}
+	} else if avail < -epsilon {
+		return 0, false, ErrHeight
+	}
+
+	// the header rows are drawn from copies of their Controllers, so that they can be drawn again
+	y := area.URY
+	if err := t.drawBand(c, area.LLX, y, 0, headEnd, true); err != nil {
+		return 0, false, err
+	}
+	y -= headHeight
+	for drawn := false; t.next < len(t.Rows); {
+		end := t.bandEnd(t.next)
+		h := t.rowsHeight(t.next, end)
+		if h <= y-area.LLY+epsilon {
+			if err := t.drawBand(c, area.LLX, y, t.next, end, false); err != nil {
+				return 0, false, err
+			}
+			y -= h
+			t.next, t.split, drawn = end, false, true
+			continue
+		}
+		if end == t.next+1 && (t.SplitRows || !drawn) {
+			h, err := t.drawSplit(c, area.LLX, y, y-area.LLY)
+			if err != nil {
+				return 0, false, err
+			}
+			y -= h
+		}
+		break
+	}
+	return area.URY - y, t.next < len(t.Rows), nil
+}
+
+// Height returns the height, in points, of the area that Draw needs to draw the rest of t, including its header rows. If t
+// has not yet been laid out, it is laid out at the given width.
+func (t *Table) Height(width float64) (float64, error) {
+	if t.cols == nil {
+		if err := t.layout(width); err != nil {
+			return 0, err
+		}
+	}
+	headEnd := t.headEnd()
+	return t.rowsHeight(0, headEnd) + t.rowsHeight(max(t.next, headEnd), len(t.Rows)), nil
+}
+
+// very slight differences due to floating point precision errors should be ok
+const epsilon = 1e-8
+
+// Width returns the width, in points, of t, once it has been laid out by a call to Draw.
+func (t *Table) Width() float64 {
+	var w float64
+	for _, cw := range t.cols {
+		w += cw
+	}
+	return w
+}
+
+// headEnd returns the index of the row that follows t's header rows, including any rows that header cells span.
+func (t *Table) headEnd() int {
+	end := 0
+	for end < min(t.HeaderRows, len(t.Rows)) {
+		end = t.bandEnd(end)
+	}
+	return end
+}
+
+// bandEnd returns the index of the row that follows the smallest sequence of rows, beginning with row i, that is not
+// spanned by any cell that begins outside of it.
+func (t *Table) bandEnd(i int) int {
+	end := i + 1
+	for _, cl := range t.cells {
+		if cl.row >= i && cl.row < end {
+			end = max(end, cl.row+cl.rowSpan)
+		}
+	}
+	return end
+}
+
+// rowsHeight returns the combined height of t's rows from row i up to row j.
+func (t *Table) rowsHeight(i, j int) float64 {
+	var h float64
+	for ; i < j; i++ {
+		h += t.heights[i]
+	}
+	return h
+}
+
+// fill returns the background color of cl.
+func (t *Table) fill(cl cell) gdf.Color {
+	if cl.Fill != nil {
+		return cl.Fill
+	}
+	return t.Rows[cl.row].Fill
+}
+
+// rect returns the area occupied by cl when the top of row i is at y, and the left edge of the table is at x.
+func (t *Table) rect(cl cell, x, y float64, i int) gdf.Rect {
+	for k := 0; k < cl.col; k++ {
+		x += t.cols[k]
+	}
+	y -= t.rowsHeight(i, cl.row)
+	w := 0.0
+	for k := cl.col; k < cl.col+cl.colSpan; k++ {
+		w += t.cols[k]
+	}
+	return gdf.Rect{LLX: x, LLY: y - t.rowsHeight(cl.row, cl.row+cl.rowSpan), URX: x + w, URY: y}
+}
+
+// textArea returns the area of r, the area of a cell, to which text of the given height is drawn with the vertical
+// alignment va.
+func (t *Table) textArea(r gdf.Rect, height float64, va VAlignment) gdf.Rect {
+	r = r.Bounds(t.Padding)
+	switch va {
+	case Middle:
+		r.URY -= (r.Height() - height) / 2
+	case Bottom:
+		r.URY = r.LLY + height
+	}
+	return r
+}
+
+// drawBand draws t's rows from row i up to row j, whose top is at y. The left edge of the table is at x. If clone is true,
+// the cells' text is drawn from copies of their Controllers.
+func (t *Table) drawBand(c *gdf.ContentStream, x, y float64, i, j int, clone bool) error {
+	var cells []cell
+	for _, cl := range t.cells {
+		if cl.row >= i && cl.row < j {
+			cells = append(cells, cl)
+		}
+	}
+	for _, cl := range cells {
+		if f := t.fill(cl); f != nil {
+			drawRect(c, t.rect(cl, x, y, i), f)
+		}
+	}
+	for _, cl := range cells {
+		if cl.tc == nil || cl.tc.IsDone {
+			continue
+		}
+		tc := cl.tc
+		if clone {
+			cp := *tc
+			tc = &cp
+		}
+		va := cl.VAlign
+		if i == t.next && t.split {
+			// the remainder of a split row continues at the top of its cells
+			va = Top
+		}
+		// the cell is at least as tall as its text, so this draws all of it
+		if _, _, err := tc.DrawText(c, t.textArea(t.rect(cl, x, y, i), tc.Height(), va)); err != nil {
+			return err
+		}
+	}
+	for _, cl := range cells {
+		t.drawBorder(c, t.rect(cl, x, y, i))
+	}
+	return nil
+}
+
+// splitHeight returns the height of the part of t's next row that can be drawn in the given height, or 0 if none of the
+// row's text fits in it.
+func (t *Table) splitHeight(height float64) float64 {
+	var h float64
+	for _, cl := range t.cells {
+		if cl.row == t.next && cl.tc != nil {
+			h = max(h, cl.tc.FitHeight(height-t.Padding.Top-t.Padding.Bottom))
+		}
+	}
+	if h == 0 {
+		return 0
+	}
+	return h + t.Padding.Top + t.Padding.Bottom
+}
+
+// splitRemainder returns the height of the part of t's next row that has not been drawn.
+func (t *Table) splitRemainder() float64 {
+	var h float64
+	for _, cl := range t.cells {
+		if cl.row == t.next && cl.tc != nil {
+			h = max(h, cl.tc.Height())
+		}
+	}
+	return h + t.Padding.Top + t.Padding.Bottom
+}
+
+// drawSplit draws as much of t's next row, whose top is at y, as fits in the given height, and returns the height of the
+// part that was drawn. The left edge of the table is at x.
+func (t *Table) drawSplit(c *gdf.ContentStream, x, y, height float64) (float64, error) {
+	h := t.splitHeight(height)
+	if h == 0 {
+		return 0, nil
+	}
+	var cells []cell
+	for _, cl := range t.cells {
+		if cl.row == t.next {
+			cells = append(cells, cl)
+		}
+	}
+	rect := func(cl cell) gdf.Rect {
+		r := t.rect(cl, x, y, t.next)
+		r.LLY = y - h
+		return r
+	}
+	for _, cl := range cells {
+		if f := t.fill(cl); f != nil {
+			drawRect(c, rect(cl), f)
+		}
+	}
+	for _, cl := range cells {
+		if cl.tc != nil && !cl.tc.IsDone {
+			r := rect(cl).Bounds(t.Padding)
+			if cl.tc.FitHeight(r.Height()) > 0 {
+				if _, _, err := cl.tc.DrawText(c, r); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	for _, cl := range cells {
+		t.drawBorder(c, rect(cl))
+	}
+	t.split = true
+	t.heights[t.next] = t.splitRemainder()
+	return h, nil
+}
+
+// drawRect fills r with color.
+func drawRect(c *gdf.ContentStream, r gdf.Rect, color gdf.Color) {
+	if !cmpColor(c.NColor, color) {
+		c.SetColor(color)
+	}
+	c.Re2(r)
+	c.Fill(gdf.NonZero)
+}
+
+// drawBorder strokes the outline of r with t's Border, if it has one.
+func (t *Table) drawBorder(c *gdf.ContentStream, r gdf.Rect) {
+	if t.Border.Width <= 0 {
+		return
+	}
+	color := t.Border.Color
+	if color == nil {
+		color = gdf.Black
+	}
+	if !cmpColor(c.SColor, color) {
+		c.SetColorStroke(color)
+	}
+	if c.LineWidth != t.Border.Width {
+		c.SetLineWidth(t.Border.Width)
+	}
+	c.Re2(r)
+	c.Stroke()
+}
+
+func cmpColor(a, b gdf.Color) bool {
+	switch v := a.(type) {
+	case gdf.RGBColor:
+		if rb, ok := b.(gdf.RGBColor); ok {
+			return v == rb
+		}
+	case gdf.CMYKColor:
+		if rb, ok := b.(gdf.CMYKColor); ok {
+			return v == rb
+		}
+	case gdf.GColor:
+		if rb, ok := b.(gdf.GColor); ok {
+			return v == rb
+		}
+	case nil:
+		if b == nil {
+			return true
+		}
+	}
+	return false
+}
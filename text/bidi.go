@@ -0,0 +1,563 @@
+package text
+
+import (
+	"slices"
+
+	"github.com/go-text/typesetting/unicodedata"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// A Direction is the base direction of a paragraph of text.
+type Direction uint
+
+const (
+	// The direction of each paragraph is that of its first strong (i.e., left-to-right or right-to-left) character,
+	// ignoring any characters between an isolate initiator and its matching PDI. Paragraphs that contain no strong
+	// characters are left-to-right.
+	AutoDirection Direction = iota
+	LeftToRight
+	RightToLeft
+)
+
+// The maximum explicit embedding level (BD2).
+const maxBidiDepth = 125
+
+// bidiClasses returns the bidi class of each rune of t.
+func bidiClasses(t []rune) []bidi.Class {
+	out := make([]bidi.Class, len(t))
+	for i, r := range t {
+		p, _ := bidi.LookupRune(r)
+		out[i] = p.Class()
+	}
+	return out
+}
+
+// bidiLevels resolves the embedding level of each rune of the paragraph t according to the rules of the Unicode Bidirectional
+// Algorithm (UAX #9), up to but not including the line-level rules, and returns the levels and the paragraph embedding level.
+// classes must hold the bidi class of each rune of t. The levels of runes removed by rule X9 are set to those of the preceding
+// rune, or to the paragraph level if there is none.
+func bidiLevels(t []rune, classes []bidi.Class, dir Direction) ([]uint8, uint8) {
+	p := bidiParagraph{
+		text:    t,
+		initial: classes,
+		types:   slices.Clone(classes),
+		levels:  make([]uint8, len(t)),
+	}
+	p.matchIsolates()
+	switch dir {
+	case LeftToRight:
+		p.level = 0
+	case RightToLeft:
+		p.level = 1
+	default:
+		if p.firstStrong(0, len(t)) == bidi.R {
+			p.level = 1
+		}
+	}
+	p.explicitLevels()
+	for _, seq := range p.isolatingRunSequences() {
+		p.resolveWeak(seq)
+		p.resolveBrackets(seq)
+		p.resolveNeutrals(seq)
+		p.resolveImplicit(seq)
+	}
+	prev := p.level
+	for i := range p.levels {
+		if isRemovedByX9(p.initial[i]) {
+			p.levels[i] = prev
+		}
+		prev = p.levels[i]
+	}
+	return p.levels, p.level
+}
+
+// visualOrder returns the indices of items with the given embedding levels in the order in which they should be displayed,
+// according to rule L2 of UAX #9.
+func visualOrder(levels []uint8) []int {
+	out := make([]int, len(levels))
+	for i := range out {
+		out[i] = i
+	}
+	if len(levels) == 0 {
+		return out
+	}
+	hi, lo := slices.Max(levels), slices.Min(levels)
+	if lo%2 == 0 {
+		lo++
+	}
+	for l := hi; l >= lo; l-- {
+		for i := 0; i < len(levels); {
+			if levels[out[i]] < l {
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(levels) && levels[out[j]] >= l {
+				j++
+			}
+			slices.Reverse(out[i:j])
+			i = j
+		}
+	}
+	return out
+}
+
+// A bidiParagraph holds the state used to resolve the embedding levels of a paragraph.
+type bidiParagraph struct {
+	text     []rune
+	initial  []bidi.Class // the original bidi classes
+	types    []bidi.Class // the bidi classes, as modified by the resolution rules
+	levels   []uint8
+	level    uint8 // the paragraph embedding level
+	matching []int // the index of the matching PDI of each isolate initiator, or -1
+}
+
+func isIsolateInitiator(c bidi.Class) bool { return c == bidi.LRI || c == bidi.RLI || c == bidi.FSI }
+
+func isRemovedByX9(c bidi.Class) bool {
+	switch c {
+	case bidi.LRE, bidi.RLE, bidi.LRO, bidi.RLO, bidi.PDF, bidi.BN:
+		return true
+	}
+	return false
+}
+
+// isNI reports whether c is a neutral or isolate formatting character.
+func isNI(c bidi.Class) bool {
+	switch c {
+	case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		return true
+	}
+	return false
+}
+
+// strongDir returns the strong direction of c for the purposes of rules N0 and N1, in which European and Arabic numbers
+// act as right-to-left characters, or ON if c is not strong.
+func strongDir(c bidi.Class) bidi.Class {
+	switch c {
+	case bidi.L:
+		return bidi.L
+	case bidi.R, bidi.AL, bidi.EN, bidi.AN:
+		return bidi.R
+	}
+	return bidi.ON
+}
+
+func dirOfLevel(l uint8) bidi.Class {
+	if l%2 == 0 {
+		return bidi.L
+	}
+	return bidi.R
+}
+
+// matchIsolates finds the matching PDI of each isolate initiator (BD9).
+func (p *bidiParagraph) matchIsolates() {
+	p.matching = make([]int, len(p.text))
+	var open []int
+	for i, c := range p.initial {
+		p.matching[i] = -1
+		switch {
+		case isIsolateInitiator(c):
+			open = append(open, i)
+		case c == bidi.PDI && len(open) != 0:
+			p.matching[open[len(open)-1]] = i
+			open = open[:len(open)-1]
+		}
+	}
+}
+
+// firstStrong returns the class, L or R, of the first strong character in t[start:end] that is not enclosed by an isolate
+// initiator and its matching PDI, or ON if there is none (P2).
+func (p *bidiParagraph) firstStrong(start, end int) bidi.Class {
+	for i := start; i < end; i++ {
+		switch c := p.initial[i]; {
+		case c == bidi.L:
+			return bidi.L
+		case c == bidi.R || c == bidi.AL:
+			return bidi.R
+		case isIsolateInitiator(c):
+			if p.matching[i] < 0 {
+				return bidi.ON
+			}
+			i = p.matching[i]
+		}
+	}
+	return bidi.ON
+}
+
+// explicitLevels applies rules X1 through X8.
+func (p *bidiParagraph) explicitLevels() {
+	type status struct {
+		level    uint8
+		override bidi.Class // L, R, or ON if there is no override
+		isolate  bool
+	}
+	stack := make([]status, 1, maxBidiDepth+2)
+	stack[0] = status{level: p.level, override: bidi.ON}
+	var overflowIsolates, overflowEmbeddings, validIsolates int
+
+	for i, c := range p.initial {
+		top := stack[len(stack)-1]
+		switch c {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.RLI, bidi.LRI, bidi.FSI:
+			isolate := isIsolateInitiator(c)
+			rtl := c == bidi.RLE || c == bidi.RLO || c == bidi.RLI
+			if c == bidi.FSI {
+				end := p.matching[i]
+				if end < 0 {
+					end = len(p.text)
+				}
+				rtl = p.firstStrong(i+1, end) == bidi.R
+			}
+			p.levels[i] = top.level
+			if isolate && top.override != bidi.ON {
+				p.types[i] = top.override
+			}
+			var level uint8
+			if rtl {
+				level = (top.level + 1) | 1
+			} else {
+				level = (top.level + 2) &^ 1
+			}
+			if level <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				override := bidi.ON
+				switch c {
+				case bidi.LRO:
+					override = bidi.L
+				case bidi.RLO:
+					override = bidi.R
+				}
+				if isolate {
+					validIsolates++
+				}
+				stack = append(stack, status{level: level, override: override, isolate: isolate})
+			} else if isolate {
+				overflowIsolates++
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidi.PDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			p.levels[i] = top.level
+			if top.override != bidi.ON {
+				p.types[i] = top.override
+			}
+		case bidi.PDF:
+			p.levels[i] = top.level
+			if overflowIsolates > 0 {
+				// do nothing
+			} else if overflowEmbeddings > 0 {
+				overflowEmbeddings--
+			} else if !top.isolate && len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case bidi.B:
+			p.levels[i] = p.level
+		default:
+			p.levels[i] = top.level
+			if top.override != bidi.ON && c != bidi.BN {
+				p.types[i] = top.override
+			}
+		}
+	}
+}
+
+// An isolatingRunSequence holds the indices of the characters of an isolating run sequence (BD13), along with the
+// directions of its start and end (sos and eos).
+type isolatingRunSequence struct {
+	idx      []int
+	level    uint8
+	sos, eos bidi.Class
+}
+
+// isolatingRunSequences divides the characters that remain after rule X9 into isolating run sequences (X10).
+func (p *bidiParagraph) isolatingRunSequences() []isolatingRunSequence {
+	// level runs
+	var runs [][]int
+	runOf := make([]int, len(p.text)) // the index of the run that starts with each character
+	last := -1
+	for i, c := range p.initial {
+		if isRemovedByX9(c) {
+			continue
+		}
+		if last < 0 || p.levels[i] != p.levels[last] {
+			runs = append(runs, nil)
+			runOf[i] = len(runs) - 1
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], i)
+		last = i
+	}
+
+	var out []isolatingRunSequence
+	used := make([]bool, len(runs))
+	for r, run := range runs {
+		if used[r] {
+			continue // the run continues the sequence of its matching isolate initiator
+		}
+		seq := slices.Clone(run)
+		for {
+			end := seq[len(seq)-1]
+			if !isIsolateInitiator(p.initial[end]) || p.matching[end] < 0 {
+				break
+			}
+			m := p.matching[end]
+			if isRemovedByX9(p.initial[m]) || len(runs) <= runOf[m] || runs[runOf[m]][0] != m {
+				break
+			}
+			used[runOf[m]] = true
+			seq = append(seq, runs[runOf[m]]...)
+		}
+
+		first, end := seq[0], seq[len(seq)-1]
+		level := p.levels[first]
+		prev, next := p.level, p.level
+		for i := first - 1; i >= 0; i-- {
+			if !isRemovedByX9(p.initial[i]) {
+				prev = p.levels[i]
+				break
+			}
+		}
+		if !isIsolateInitiator(p.initial[end]) || p.matching[end] >= 0 {
+			for i := end + 1; i < len(p.text); i++ {
+				if !isRemovedByX9(p.initial[i]) {
+					next = p.levels[i]
+					break
+				}
+			}
+		}
+		out = append(out, isolatingRunSequence{
+			idx:   seq,
+			level: level,
+			sos:   dirOfLevel(max(prev, level)),
+			eos:   dirOfLevel(max(next, level)),
+		})
+	}
+	return out
+}
+
+// resolveWeak applies rules W1 through W7 to seq.
+func (p *bidiParagraph) resolveWeak(seq isolatingRunSequence) {
+	t := p.types
+	// W1
+	prev := seq.sos
+	for _, i := range seq.idx {
+		if t[i] == bidi.NSM {
+			t[i] = prev
+		}
+		prev = t[i]
+		if isIsolateInitiator(prev) || prev == bidi.PDI {
+			prev = bidi.ON
+		}
+	}
+	// W2, W3
+	strong := seq.sos
+	for _, i := range seq.idx {
+		switch t[i] {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = t[i]
+		case bidi.EN:
+			if strong == bidi.AL {
+				t[i] = bidi.AN
+			}
+		}
+	}
+	for _, i := range seq.idx {
+		if t[i] == bidi.AL {
+			t[i] = bidi.R
+		}
+	}
+	// W4
+	for k := 1; k+1 < len(seq.idx); k++ {
+		i := seq.idx[k]
+		before, after := t[seq.idx[k-1]], t[seq.idx[k+1]]
+		switch {
+		case t[i] == bidi.ES && before == bidi.EN && after == bidi.EN:
+			t[i] = bidi.EN
+		case t[i] == bidi.CS && before == after && (before == bidi.EN || before == bidi.AN):
+			t[i] = before
+		}
+	}
+	// W5
+	for k := 0; k < len(seq.idx); k++ {
+		if t[seq.idx[k]] != bidi.ET {
+			continue
+		}
+		end := k
+		for end < len(seq.idx) && t[seq.idx[end]] == bidi.ET {
+			end++
+		}
+		if (k > 0 && t[seq.idx[k-1]] == bidi.EN) || (end < len(seq.idx) && t[seq.idx[end]] == bidi.EN) {
+			for _, i := range seq.idx[k:end] {
+				t[i] = bidi.EN
+			}
+		}
+		k = end
+	}
+	// W6
+	for _, i := range seq.idx {
+		switch t[i] {
+		case bidi.ES, bidi.ET, bidi.CS:
+			t[i] = bidi.ON
+		}
+	}
+	// W7
+	strong = seq.sos
+	for _, i := range seq.idx {
+		switch t[i] {
+		case bidi.L, bidi.R:
+			strong = t[i]
+		case bidi.EN:
+			if strong == bidi.L {
+				t[i] = bidi.L
+			}
+		}
+	}
+}
+
+// The maximum number of nested opening brackets tracked by rule BD16.
+const maxBracketDepth = 63
+
+// pairedBracket returns the closing bracket paired with the opening bracket r, accounting for the canonical equivalence
+// of the angle brackets U+2329/U+232A and U+3008/U+3009.
+func pairedBracket(r rune) rune {
+	m, _ := unicodedata.LookupMirrorChar(r)
+	return canonicalBracket(m)
+}
+
+func canonicalBracket(r rune) rune {
+	switch r {
+	case '\u2329':
+		return '\u3008'
+	case '\u232A':
+		return '\u3009'
+	}
+	return r
+}
+
+// resolveBrackets applies rule N0 to seq.
+func (p *bidiParagraph) resolveBrackets(seq isolatingRunSequence) {
+	t := p.types
+	// BD16: identify the bracket pairs, as positions within seq
+	type opener struct {
+		close rune
+		pos   int
+	}
+	var stack []opener
+	var pairs [][2]int
+find:
+	for k, i := range seq.idx {
+		if t[i] != bidi.ON {
+			continue
+		}
+		prop, _ := bidi.LookupRune(p.text[i])
+		if !prop.IsBracket() {
+			continue
+		}
+		if prop.IsOpeningBracket() {
+			if len(stack) == maxBracketDepth {
+				break find
+			}
+			stack = append(stack, opener{close: pairedBracket(p.text[i]), pos: k})
+			continue
+		}
+		r := canonicalBracket(p.text[i])
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j].close == r {
+				pairs = append(pairs, [2]int{stack[j].pos, k})
+				stack = stack[:j]
+				break
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(a, b [2]int) int { return a[0] - b[0] })
+
+	embedding := dirOfLevel(seq.level)
+	for _, pair := range pairs {
+		dir := bidi.ON
+		for _, i := range seq.idx[pair[0]+1 : pair[1]] {
+			if d := strongDir(t[i]); d == embedding {
+				dir = embedding
+				break
+			} else if d != bidi.ON {
+				dir = d
+			}
+		}
+		if dir == bidi.ON {
+			continue // N0 d
+		}
+		if dir != embedding {
+			// N0 c: use the direction of the preceding context if it matches the opposite direction
+			context := seq.sos
+			for k := pair[0] - 1; k >= 0; k-- {
+				if d := strongDir(t[seq.idx[k]]); d != bidi.ON {
+					context = d
+					break
+				}
+			}
+			if context != dir {
+				dir = embedding
+			}
+		}
+		for _, k := range pair {
+			t[seq.idx[k]] = dir
+			// any NSMs that originally followed the bracket take its new direction
+			for n := k + 1; n < len(seq.idx) && p.initial[seq.idx[n]] == bidi.NSM; n++ {
+				t[seq.idx[n]] = dir
+			}
+		}
+	}
+}
+
+// resolveNeutrals applies rules N1 and N2 to seq.
+func (p *bidiParagraph) resolveNeutrals(seq isolatingRunSequence) {
+	t := p.types
+	embedding := dirOfLevel(seq.level)
+	for k := 0; k < len(seq.idx); k++ {
+		if !isNI(t[seq.idx[k]]) {
+			continue
+		}
+		end := k
+		for end < len(seq.idx) && isNI(t[seq.idx[end]]) {
+			end++
+		}
+		before, after := seq.sos, seq.eos
+		if k > 0 {
+			before = strongDir(t[seq.idx[k-1]])
+		}
+		if end < len(seq.idx) {
+			after = strongDir(t[seq.idx[end]])
+		}
+		dir := embedding
+		if before == after && before != bidi.ON {
+			dir = before
+		}
+		for _, i := range seq.idx[k:end] {
+			t[i] = dir
+		}
+		k = end
+	}
+}
+
+// resolveImplicit applies rules I1 and I2 to seq.
+func (p *bidiParagraph) resolveImplicit(seq isolatingRunSequence) {
+	for _, i := range seq.idx {
+		switch l := p.levels[i]; {
+		case l%2 == 0 && p.types[i] == bidi.R:
+			p.levels[i]++
+		case l%2 == 0 && (p.types[i] == bidi.AN || p.types[i] == bidi.EN):
+			p.levels[i] += 2
+		case l%2 == 1 && (p.types[i] == bidi.L || p.types[i] == bidi.EN || p.types[i] == bidi.AN):
+			p.levels[i]++
+		}
+	}
+}
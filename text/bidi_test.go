@@ -0,0 +1,86 @@
+package text
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// bidiCharacterTests are test cases in the format of the Unicode Character Database's BidiCharacterTest.txt: the code points
+// of a paragraph; its direction (0 for LTR, 1 for RTL, 2 for auto); the resolved paragraph embedding level; the resolved
+// level of each character, or x for characters removed by rule X9; and the visual order of the characters that are not
+// removed. None of the cases ends with whitespace, so that the line-level rule L1, which the Controller applies when it
+// arranges the items of a line, does not change their levels.
+const bidiCharacterTests = `
+# plain text
+0061 0062 0063;0;0;0 0 0;0 1 2
+05D0 05D1 05D2;0;0;1 1 1;2 1 0
+05D0 0020 0061;2;1;1 1 2;2 1 0
+0031 0032;2;0;0 0;0 1
+0020 0061 0020 0062;1;1;1 2 2 2;1 2 3 0
+# weak types
+05D0 0020 0031 0032;1;1;1 1 2 2;2 3 1 0
+05D0 0020 0031 002C 0032;1;1;1 1 2 2 2;2 3 4 1 0
+05D0 0020 0024 0031;1;1;1 1 2 2;2 3 1 0
+0627 0031 0032;0;0;1 2 2;1 2 0
+0661 0662;0;0;2 2;0 1
+05D0 0308 0061;0;0;1 1 0;1 0 2
+# paired brackets
+0061 0028 0062 0029 05D0;1;1;2 2 2 2 1;4 0 1 2 3
+05D0 0028 05D1 0029 0061;0;0;1 1 1 1 0;3 2 1 0 4
+# explicit embeddings and isolates
+0061 202B 0062 202C 0063;0;0;0 x 2 x 0;0 2 4
+05D0 2066 0061 0020 0062 2069 05D1;1;1;1 1 2 2 2 1 1;6 5 2 3 4 1 0
+2068 05D0 2069 0061;2;0;0 1 0 0;0 1 2 3
+`
+
+func TestBidiCharacters(t *testing.T) {
+	for _, line := range strings.Split(bidiCharacterTests, "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) != 5 {
+			t.Fatalf("malformed test case %q", line)
+		}
+		var text []rune
+		for _, s := range strings.Fields(fields[0]) {
+			r, err := strconv.ParseUint(s, 16, 32)
+			if err != nil {
+				t.Fatalf("malformed test case %q: %v", line, err)
+			}
+			text = append(text, rune(r))
+		}
+		dir := [...]Direction{LeftToRight, RightToLeft, AutoDirection}[fields[1][0]-'0']
+
+		levels, para := bidiLevels(text, bidiClasses(text), dir)
+		if want := fields[2]; strconv.Itoa(int(para)) != want {
+			t.Errorf("%s: paragraph level = %d, want %s", fields[0], para, want)
+		}
+		var got []string
+		var kept []int // the indices of the characters that are not removed by X9
+		for i, l := range levels {
+			if isRemovedByX9(bidiClasses(text[i : i+1])[0]) {
+				got = append(got, "x")
+				continue
+			}
+			got = append(got, strconv.Itoa(int(l)))
+			kept = append(kept, i)
+		}
+		if want := strings.Fields(fields[3]); !slices.Equal(got, want) {
+			t.Errorf("%s: levels = %v, want %v", fields[0], got, want)
+		}
+		keptLevels := make([]uint8, len(kept))
+		for i, k := range kept {
+			keptLevels[i] = levels[k]
+		}
+		var order []string
+		for _, i := range visualOrder(keptLevels) {
+			order = append(order, strconv.Itoa(kept[i]))
+		}
+		if want := strings.Fields(fields[4]); !slices.Equal(order, want) {
+			t.Errorf("%s: visual order = %v, want %v", fields[0], order, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package text
+
+import "github.com/cdillond/gdf"
+
+// A LineBounds function returns the bounds of a line of a Controller's text whose top and bottom are the given distances, in
+// points, below the top of the text: the distance between the left edge of the area in which the text is drawn and the start
+// of the line, and the width of the line. Each line is set at the first position, at or below the bottom of the line above
+// it, at which its bounds, less the line's indents, are wide enough for its first word; the space above it is left empty.
+// The distances are measured from the top of the text as if all of it were drawn to a single area, not from the top of each
+// area to which it is drawn, and they include the space that a line's paragraph style leaves above it, but not the space
+// added by a vertical alignment.
+type LineBounds func(top, bottom float64) (offset, width float64)
+
+// the maximum number of times that a line is moved down to find bounds in which it fits
+const maxLineMoves = 1000
+
+// A linePos is the position of a line: the space left empty above it, the distance from the top of the text to its bottom,
+// and its bounds.
+type linePos struct {
+	skip, bottom    float64
+	offset, measure float64
+}
+
+// slot returns the position of the line of paragraph p that consists of tc.tokens[start:end] and is broken at
+// tc.tokens[end], when the bottom of the line above it is y points below the top of the text and the space before is left
+// between them, along with the width available to the line's text. The line is moved down, by its own height at a time,
+// until its bounds are at least need points wide, or, if they never are, it is left where it is.
+func (tc *Controller) slot(y, before, need float64, start, end, p int) (linePos, float64) {
+	indents := tc.indents(p)
+	if tc.lineBounds == nil {
+		return linePos{measure: tc.lineWidth}, tc.lineWidth - indents
+	}
+	asc, desc, _ := tc.lineExtent(start, end)
+	step := max(asc+desc, 1)
+	at := func(skip float64) linePos {
+		base := tc.snap(y + before + skip + asc)
+		pos := linePos{skip: skip, bottom: base + desc}
+		pos.offset, pos.measure = tc.lineBounds(base-asc, base+desc)
+		return pos
+	}
+	for k := range maxLineMoves {
+		if pos := at(float64(k) * step); pos.measure-indents > 0 && pos.measure-indents >= need-epsilon {
+			return pos, pos.measure - indents
+		}
+	}
+	pos := at(0)
+	return pos, pos.measure - indents
+}
+
+// leadWidths returns the width, in points, of the run of unbreakable tokens that begins at each of tc's tokens, if tc has
+// LineBounds.
+func (tc *Controller) leadWidths() []float64 {
+	out := make([]float64, len(tc.tokens)+1)
+	if tc.lineBounds == nil {
+		return out
+	}
+	for i := len(tc.tokens) - 1; i >= 0; i-- {
+		switch v := tc.tokens[i].(type) {
+		case box:
+			out[i] = v.Width() + out[i+1]
+		case inlineBox:
+			out[i] = v.Width() + out[i+1]
+		case boundSkip:
+			out[i] = v.Width() + out[i+1]
+		case flIndent:
+			out[i] = out[i+1]
+		}
+	}
+	return out
+}
+
+// Wrap returns a LineBounds function that confines each line of text drawn in area to the widest part of area, between
+// the line's top and bottom, that does not intersect any of exclusions, such as the rectangles, expanded by the desired
+// gutter, of images drawn within area. The exclusions are in the same coordinates as area. Parts of area that are narrower
+// than minWidth are treated as excluded, and lines that extend below area are as wide as area. A Controller that uses the
+// function should be created with a lineWidth equal to area's width.
+func Wrap(area gdf.Rect, minWidth float64, exclusions ...gdf.Rect) LineBounds {
+	return func(top, bottom float64) (float64, float64) {
+		top, bottom = area.URY-top, area.URY-bottom
+		if bottom < area.LLY-epsilon {
+			return 0, area.Width()
+		}
+		// the free intervals of the line, from left to right
+		free := [][2]float64{{area.LLX, area.URX}}
+		for _, ex := range exclusions {
+			if ex.LLY >= top || ex.URY <= bottom {
+				continue
+			}
+			var next [][2]float64
+			for _, iv := range free {
+				if ex.URX <= iv[0] || ex.LLX >= iv[1] {
+					next = append(next, iv)
+					continue
+				}
+				if ex.LLX > iv[0] {
+					next = append(next, [2]float64{iv[0], ex.LLX})
+				}
+				if ex.URX < iv[1] {
+					next = append(next, [2]float64{ex.URX, iv[1]})
+				}
+			}
+			free = next
+		}
+		var best [2]float64
+		for _, iv := range free {
+			if iv[1]-iv[0] > best[1]-best[0] {
+				best = iv
+			}
+		}
+		if best[1]-best[0] < minWidth {
+			return 0, 0
+		}
+		return best[0] - area.LLX, best[1] - best[0]
+	}
+}
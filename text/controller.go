@@ -1,10 +1,12 @@
 package text
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"math"
 	"slices"
+	"unicode"
 
 	"github.com/cdillond/gdf"
 )
@@ -18,73 +20,138 @@ type DrawErr string
 func (d DrawErr) Error() string { return string(d) }
 
 const (
-	// errors returned by NewController
+	// errors returned by NewController and NewSpanController
 	ErrTolerance = ControllerErr("unable to break lines using current tolerances")
 	ErrWordSize  = ControllerErr("source text contains an unbreakable word that is longer than the maximum line length")
+	ErrColor     = ControllerErr("source text contains an invalid color directive")
+	ErrFont      = ControllerErr("source text contains a span with no font")
 
 	// errors returned by DrawText
 	ErrWidth   = DrawErr("target area must be at least as wide as the maximum line width")
 	ErrEmpty   = DrawErr("source text buffer is empty")
-	ErrLeading = DrawErr("font leading must be greater than 0")
-	ErrHeight  = DrawErr("target area must be at least as tall as the font leading")
+	ErrLeading = DrawErr("font leading or line height must be greater than 0")
+	ErrHeight  = DrawErr("target area must be at least as tall as the next line of text")
 )
 
 // A Controller is a struct that aids in writing text to a ContentStream. The Controller can break text into lines and paragraphs,
 // determine the appropriate kerning for glyphs in a string, and draw text according to the format specified by the ControllerCfg struct.
 type Controller struct {
-	IsDone         bool
-	src            []rune        // source text
-	family         FontFamily    // the set of fonts to be used. On each call to DrawText, the supplied ContentStream's font will be set to one of the fonts from f, according to the TextConroller's font weight state. The fonts need not be of the same actual family - chosen families are valid, too!
-	isBold, isItal bool          // font weight state
-	curFont        *gdf.Font     // Used for calculating line widths, but not for actually writing the text.
-	fontSize       float64       // font size. On each call to DrawText, the supplied ContentStream's FontSize will be set to this value
-	leading        float64       // text leading. On each call to DrawText, the supplied ContentStream's Leading will be set to this value.
-	lineWidth      float64       // ideal line width in Font Units
-	alignment      Alignment     // paragraph alignment
-	just           Justification // paragraph justification style
-	firstIndent    float64       // indent of the first line of each paragraph
-	tokens         []token       // source text tokens
-	breakpoints    []int         // indices of forced breaks in k.tokens
-	lineWidths     []float64     // actual line widths, unjustified
-	adjs           []float64     // adjustments (in font units) to the spaces ('\x20') in each line
-	tightness      float64       // the ratio of the minimum allowable space advance and the normal space advance in justified text
-	looseness      float64       // the ratio of the maximum allowable space advance and the normal space advance in justified text
-	scolor, ncolor gdf.Color
-	renderMode     gdf.RenderMode
-	n              int // token index
-	ln             int // line index
+	IsDone      bool
+	spans       []Span        // source text, with the defaults filled in and divided among the fonts that set it
+	srcSpans    []int         // the index of the Span from which each of spans was taken
+	hyphs       []*Hyphenator // the Hyphenator of each span; nil if its words are only hyphenated at soft hyphens
+	fontSize    float64       // the default font size
+	leading     float64       // text leading. On each call to DrawText, the supplied ContentStream's Leading will be set to this value.
+	lineHeight  float64       // the ratio of the height of each text run's line box and the sum of its ascent and descent
+	lineWidth   float64       // ideal line width in points
+	defStyle    ParaStyle     // the style of paragraphs that begin in spans without a ParaStyle
+	styles      []ParaStyle   // the style of each paragraph
+	list        *List         // the list whose items are tc's paragraphs, if any
+	items       int           // the number of tc's list items
+	lineBounds  LineBounds    // the bounds of each line, if they vary
+	vAlign      VAlignment    // the vertical alignment of the lines in each area
+	grid        float64       // the spacing of the baseline grid; 0 if there is none
+	tokens      []token       // source text tokens
+	info        []tokInfo     // the bidi embedding levels and spans of the tokens
+	lines       []line        // the lines into which the tokens have been broken
+	decorated   bool          // whether any span is decorated or highlighted
+	linked      bool          // whether any span is a link
+	tabStops    []TabStop     // tab stops, in ascending order of position
+	tabInterval float64       // the distance between the default tab stops that follow the last of tabStops
+	tightness   float64       // the ratio of the minimum allowable space advance and the normal space advance in justified text
+	looseness   float64       // the ratio of the maximum allowable space advance and the normal space advance in justified text
+	scolor      gdf.Color
+	ncolor      gdf.Color
+	renderMode  gdf.RenderMode
+	features    []gdf.Feature // OpenType feature settings
+	direction   Direction     // base direction of each paragraph
+	minLeft     int           // minimum number of letters before a hyphenation point
+	minRight    int           // minimum number of letters after a hyphenation point
+	maxHyphens  int           // maximum number of consecutive hyphenated lines; 0 if unlimited
+	ln          int           // line index
 }
 
 // A ControllerCfg specifies options for the formatting of text drawn by a Controller.
 type ControllerCfg struct {
 	Alignment
 	Justification
-	RenderMode     gdf.RenderMode
-	FontSize       float64
-	Leading        float64
-	IsIndented     bool
+	RenderMode  gdf.RenderMode
+	FontSize    float64
+	Leading     float64 // the distance between the baselines of lines set in the default font and size, if LineHeight is 0
+	LineHeight  float64 // the ratio of a line's height and the sum of the ascent and descent of its tallest text, as in CSS
+	IsIndented  bool    // whether the first line of each paragraph is indented by 4 spaces, if FirstIndent is 0
+	FirstIndent float64 // the indent, in points, of the first line of each paragraph; if it is negative, the line hangs
+	LeftIndent  float64 // the indent, in points, of each line from the left edge of the area in which it is drawn
+	RightIndent float64 // the indent, in points, of each line from the right edge of the area in which it is drawn
+	// List, if not nil, is the list whose items are the paragraphs of the text. The items are indented from the starting margin
+	// by the List, in addition to any LeftIndent or RightIndent, and their markers are drawn in the space between.
+	List           *List
 	NColor, SColor gdf.Color // default nonstroking and stroking colors
 	Looseness      float64   // the ratio of the maximum allowable space advance and the normal space advance in justified text
 	Tightness      float64   // the ratio of the minimum allowable space advance and the normal space advance in justified text
 	IsBold, IsItal bool
+	Features       []gdf.Feature // OpenType feature settings (e.g., {"liga", 0} or {"smcp", 1}) used when shaping the text
+	Direction      Direction     // the base direction of each paragraph; by default, it is determined by the paragraph's text
+	// Language is the BCP 47 tag (e.g., "en-US") of the language whose hyphenation patterns are used to hyphenate words.
+	// If it is empty, or no Hyphenator is registered for it, words are only hyphenated at soft hyphens (U+00AD).
+	Language       string
+	HyphenMinLeft  int // the minimum number of letters that must precede a hyphenation point found by the patterns
+	HyphenMinRight int // the minimum number of letters that must follow a hyphenation point found by the patterns
+	MaxHyphens     int // the maximum number of consecutive lines that can end with a hyphen; 0 means no limit
+	// TabStops are the positions to which horizontal tabs ('\t') advance the text. Lines that contain tabs are aligned
+	// to the starting margin, and their spaces are not adjusted to justify them.
+	TabStops    []TabStop
+	TabInterval float64 // the distance between the default tab stops that follow the last of TabStops; if 0, 36 points
+	// LineBounds, if not nil, returns the bounds of each line, so that the text can wrap around images or fill irregular
+	// shapes. The indents of each line are measured from its bounds.
+	LineBounds LineBounds
+	// VAlign is the vertical alignment of the lines that DrawText draws to an area. Justified lines fill the area; the extra
+	// space is divided among the gaps between paragraphs, or, if there are none, the lines are drawn at the top of the area.
+	VAlign VAlignment
+	// BaselineGrid, if greater than 0, is the spacing, in points, of a grid of baselines that begins at the top of each area.
+	// Each line is moved down to the next baseline of the grid, so that the lines of text drawn to adjacent areas with the
+	// same BaselineGrid line up, even if they are set in different sizes. To align the first lines, the grid spacing should
+	// be no less than the greatest ascent of the first line of each area.
+	BaselineGrid float64
 }
 
 func NewControllerCfg(fontSize, leading float64) ControllerCfg {
 	return ControllerCfg{
-		Alignment:     Left,
-		Justification: Ragged,
-		FontSize:      fontSize,
-		Leading:       leading,
-		IsIndented:    false,
-		SColor:        nil,
-		NColor:        nil,
-		Looseness:     2,
-		Tightness:     .25,
+		Alignment:      Start,
+		Justification:  Ragged,
+		FontSize:       fontSize,
+		Leading:        leading,
+		IsIndented:     false,
+		SColor:         nil,
+		NColor:         nil,
+		Looseness:      2,
+		Tightness:      .25,
+		HyphenMinLeft:  2,
+		HyphenMinRight: 3,
+		MaxHyphens:     2,
 	}
 }
 
 type FontFamily struct {
 	Regular, Bold, Ital, BoldItal *gdf.Font
+	// Fallback holds the fonts, in order of preference, in which the characters that a span's font cannot draw are set, e.g.,
+	// a CJK font or a symbol font. Each cluster of characters is set in the first font that has glyphs for all of them and
+	// can encode them, in the size and color of its span. Since fonts that are not Composite can only encode the characters
+	// of Windows-1252, fallback fonts for other scripts must be Composite.
+	Fallback []*gdf.Font
+}
+
+// font returns the member of f with the given weight and style.
+func (f FontFamily) font(isBold, isItal bool) *gdf.Font {
+	switch {
+	case isBold && isItal:
+		return f.BoldItal
+	case isBold:
+		return f.Bold
+	case isItal:
+		return f.Ital
+	}
+	return f.Regular
 }
 
 type Alignment uint
@@ -93,8 +160,22 @@ const (
 	Left Alignment = iota
 	Right
 	Center
+	Start // aligned to the left margin in left-to-right paragraphs and to the right margin in right-to-left paragraphs
+	End   // aligned to the right margin in left-to-right paragraphs and to the left margin in right-to-left paragraphs
 )
 
+// resolve returns the Left, Right, or Center alignment equivalent to a in a paragraph with the embedding level para.
+func (a Alignment) resolve(para uint8) Alignment {
+	rtl := para%2 == 1
+	switch {
+	case a == Start && rtl, a == End && !rtl:
+		return Right
+	case a == Start, a == End:
+		return Left
+	}
+	return a
+}
+
 type Justification uint
 
 const (
@@ -115,15 +196,89 @@ to the formatting directives contained in FormatText:
  1. rune(-1) is interpreted as end of text. Any runes that appear after this character will not be parsed.
  2. rune(-2) is interpreted as a color indicator. This character must be followed by three comma-separated 3-digit integers in [0,255]
     that specify the red, green, and blue components of an RGBColor. (This is equivalent to setting the nonstroking color of the document to
-    RGBColor{R:float64(red)/255, G:float64(green)/255, B:float64(blue)/255}).
+    RGBColor{R:float64(red)/255, G:float64(green)/255, B:float64(blue)/255}). A malformed color indicator causes NewController to
+    return ErrColor.
  3. rune(-3) toggles bold text on and off.
  4. rune(-4) toggles italic text on and off.
     The bold and italic indicators can be used to switch among fonts in a given font family.
 
-NOTE: the formatting directives are not valid UTF-8 and cannot be embedded in strings.
+NOTE: the formatting directives are not valid UTF-8 and cannot be embedded in strings. Text that requires other formatting, such as
+changes of font size, can be expressed as a slice of Spans and passed to NewSpanController.
 */
 type FormatText []rune
 
+// spans converts src to a slice of Spans that use the fonts of f, beginning with the weight, style, and color specified by cfg.
+func (src FormatText) spans(f FontFamily, cfg ControllerCfg) ([]Span, error) {
+	isBold, isItal := cfg.IsBold, cfg.IsItal
+	color := cfg.NColor
+	var out []Span
+	var text []rune
+	flush := func() {
+		if len(text) != 0 {
+			out = append(out, Span{Text: string(text), Font: f.font(isBold, isItal), Color: color})
+			text = text[:0]
+		}
+	}
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case eot_tok:
+			flush()
+			return out, nil
+		case col_tok:
+			nc, ok := parseColor(src, i)
+			if !ok {
+				return nil, fmt.Errorf("%w at index %d", ErrColor, i)
+			}
+			flush()
+			color = nc
+			i += 11
+		case bold_tok:
+			flush()
+			isBold = !isBold
+		case ital_tok:
+			flush()
+			isItal = !isItal
+		default:
+			text = append(text, src[i])
+		}
+	}
+	flush()
+	return out, nil
+}
+
+// parseColor parses the color directive at src[i], if it is valid.
+func parseColor(src []rune, i int) (gdf.RGBColor, bool) {
+	if i > len(src)-12 {
+		return gdf.RGBColor{}, false
+	}
+	var r, g, b uint
+	_, err := fmt.Sscanf(string(src[i+1:i+1+9+2]), "%03d,%03d,%03d", &r, &g, &b)
+	if err != nil || r > 255 || g > 255 || b > 255 {
+		return gdf.RGBColor{}, false
+	}
+	return gdf.RGBColor{R: float64(r) / 255, G: float64(g) / 255, B: float64(b) / 255}, true
+}
+
+// A Span is a run of source text that is drawn in a single style. Its zero-valued fields take the defaults of the Controller
+// that draws it.
+type Span struct {
+	Text       string
+	Font       *gdf.Font  // if nil, the member of the Controller's FontFamily selected by the ControllerCfg's IsBold and IsItal fields
+	Size       float64    // font size in points; if 0, the ControllerCfg's FontSize
+	Color      gdf.Color  // nonstroking color; if nil, the ControllerCfg's NColor
+	Rise       float64    // the distance, in points, by which the baseline is raised, e.g., for superscripts; negative values lower it
+	Lang       string     // BCP 47 tag of the language used to hyphenate the text; if empty, the ControllerCfg's Language
+	Decoration Decoration // the lines, such as underlines, that are drawn along the text in its color
+	Highlight  gdf.Color  // if not nil, the color of a box drawn behind the text that covers its font's ascent and descent
+	Para       *ParaStyle // if not nil, the style of each paragraph that begins in the span
+	Inline     *Inline    // if not nil, the object that is drawn in place of the span's Text
+	// Dest, if not nil, is the destination within the PDF to which the span's text links, and URI, if Dest is nil, is the URI
+	// that the text opens. A link annotation is added to the page for each part of the text drawn to it, and it covers the
+	// text on each of the lines across which it is broken. The text is not otherwise marked as a link.
+	Dest *gdf.Dest
+	URI  string
+}
+
 func cmpColor(a, b gdf.Color) bool {
 	switch v := a.(type) {
 	case gdf.RGBColor:
@@ -150,65 +305,123 @@ func cmpColor(a, b gdf.Color) bool {
 // and an error if it encounters a problem while parsing and shaping src. lineWidth should be the maximum desired
 // width, in points, of each line of the output text when drawn to a gdf.ContentStream.
 func NewController(src FormatText, lineWidth float64, f FontFamily, cfg ControllerCfg) (Controller, error) {
+	spans, err := src.spans(f, cfg)
+	if err != nil {
+		return *new(Controller), err
+	}
+	return NewSpanController(spans, lineWidth, f, cfg)
+}
+
+// NewSpanController returns a Controller that is ready to write the text of spans to ContentStreams. The spans are shaped
+// separately, but lines are broken across them as if they were a single text; the height of each line is determined by
+// the ascent and descent of its tallest text. The fonts of f are used for spans that do not specify a font. It returns an invalid Controller
+// and an error if it encounters a problem while shaping the spans. lineWidth should be the maximum desired width, in points,
+// of each line of the output text when drawn to a gdf.ContentStream.
+func NewSpanController(spans []Span, lineWidth float64, f FontFamily, cfg ControllerCfg) (Controller, error) {
 	tc := Controller{
-		src:        src,
-		family:     f,
-		lineWidth:  gdf.PtToFU(lineWidth, cfg.FontSize),
-		fontSize:   cfg.FontSize,
-		alignment:  cfg.Alignment,
-		just:       cfg.Justification,
-		tightness:  cfg.Tightness,
-		looseness:  cfg.Looseness,
-		leading:    cfg.Leading,
-		isBold:     cfg.IsBold,
-		isItal:     cfg.IsItal,
-		scolor:     cfg.SColor,
-		ncolor:     cfg.NColor,
-		renderMode: cfg.RenderMode,
-	}
-	if tc.just == Ragged {
-		tc.tightness = 0
-		tc.looseness = 1
-	}
-	if tc.just == Justified {
-		// stretch tolerance cannot be 0
-		if tc.looseness == 0 {
-			tc.looseness = .5
-		}
-	}
-	if tc.isBold && tc.isItal {
-		tc.curFont = f.BoldItal
-	} else if tc.isBold {
-		tc.curFont = f.Bold
-	} else if tc.isItal {
-		tc.curFont = f.Ital
-	} else {
-		tc.curFont = f.Regular
+		lineWidth:   lineWidth,
+		fontSize:    cfg.FontSize,
+		tightness:   cfg.Tightness,
+		looseness:   cfg.Looseness,
+		leading:     cfg.Leading,
+		scolor:      cfg.SColor,
+		ncolor:      cfg.NColor,
+		renderMode:  cfg.RenderMode,
+		features:    cfg.Features,
+		direction:   cfg.Direction,
+		minLeft:     cfg.HyphenMinLeft,
+		minRight:    cfg.HyphenMinRight,
+		maxHyphens:  cfg.MaxHyphens,
+		tabStops:    slices.SortedFunc(slices.Values(cfg.TabStops), func(a, b TabStop) int { return cmp.Compare(a.Pos, b.Pos) }),
+		tabInterval: cfg.TabInterval,
+		list:        cfg.List,
+		lineBounds:  cfg.LineBounds,
+		vAlign:      cfg.VAlign,
+		grid:        cfg.BaselineGrid,
+	}
+	tc.defStyle = ParaStyle{
+		Alignment:     cfg.Alignment,
+		Justification: cfg.Justification,
+		FirstIndent:   cfg.FirstIndent,
+		LeftIndent:    cfg.LeftIndent,
+		RightIndent:   cfg.RightIndent,
+	}
+	// the tolerances apply to justified paragraphs; the stretch tolerance cannot be 0
+	if tc.looseness == 0 {
+		tc.looseness = .5
 	}
 
-	spaceAdv := f.Regular.GlyphAdvance('\u0020') // use the regular font as the baseline regardless
-	if cfg.IsIndented {
-		// indent is 4 spaces; subject to change.
-		tc.firstIndent = 4.0 * float64(spaceAdv)
+	defFont := f.font(cfg.IsBold, cfg.IsItal)
+	if len(spans) == 0 {
+		// the Controller still needs a span to which its finishing glue can belong
+		spans = []Span{{}}
+	}
+	tc.spans = make([]Span, len(spans))
+	for i, s := range spans {
+		if s.Font == nil {
+			s.Font = defFont
+		}
+		if s.Font == nil {
+			return *new(Controller), fmt.Errorf("%w: %q", ErrFont, s.Text)
+		}
+		if s.Size == 0 {
+			s.Size = cfg.FontSize
+		}
+		if s.Color == nil {
+			s.Color = cfg.NColor
+		}
+		if s.Lang == "" {
+			s.Lang = cfg.Language
+		}
+		tc.spans[i] = s
+	}
+	tc.spans, tc.srcSpans = splitFallback(tc.spans, f.Fallback)
+	tc.hyphs = make([]*Hyphenator, len(tc.spans))
+	for i, s := range tc.spans {
+		tc.hyphs[i] = lookupHyphenator(s.Lang)
+		tc.decorated = tc.decorated || s.Decoration != 0 || s.Highlight != nil
+		tc.linked = tc.linked || s.Dest != nil || s.URI != ""
 	}
 
-	tc.tokens = tc.tokenize(tc.src)
-	breakpoints, lineWidths, adjs, err := tc.breakLines(tc.tightness, tc.looseness)
-	// keep trying until it's clear there's no acceptable solution
-	if err != nil {
-		for squish, stretch := tc.tightness*1.25, tc.looseness*2; squish < 1 && errors.Is(err, ErrTolerance); {
-			breakpoints, lineWidths, adjs, err = tc.breakLines(squish, stretch)
-			squish *= 1.25
-			stretch *= 2
+	tc.lineHeight = cfg.LineHeight
+	if tc.lineHeight == 0 {
+		// lines set in the default font and size are separated by the leading
+		font := defFont
+		if font == nil {
+			font = tc.spans[0].Font
+		}
+		if em := gdf.FUToPt(float64(font.Ascent+font.Descent), cfg.FontSize); em > 0 {
+			tc.lineHeight = cfg.Leading / em
 		}
+	}
 
-		if err != nil {
-			return *new(Controller), err
+	if cfg.IsIndented && tc.defStyle.FirstIndent == 0 {
+		// use the regular font as the baseline regardless
+		base := f.Regular
+		if base == nil {
+			base = tc.spans[0].Font
 		}
+		// indent is 4 spaces; subject to change.
+		tc.defStyle.FirstIndent = 4.0 * gdf.FUToPt(float64(base.GlyphAdvance(' ')), cfg.FontSize)
+	}
+
+	tokens, info, err := tc.tokenize()
+	if err != nil {
+		return *new(Controller), err
+	}
+	tc.tokens = tokens
+	tc.info = info
+	lines, err := tc.breakLines(0)
+	// keep trying until it's clear there's no acceptable solution; ragged lines can be stretched further until the retries
+	// run out, e.g., when a word fits the width of the text but not the line on which it must be set
+	ragged := slices.ContainsFunc(tc.styles, func(s ParaStyle) bool { return s.Justification == Ragged })
+	for n := 1; errors.Is(err, ErrTolerance) && n <= maxRetries && (ragged || tc.tightness*math.Pow(1.25, float64(n)) < 1); n++ {
+		lines, err = tc.breakLines(n)
+	}
+	if err != nil {
+		return *new(Controller), err
 	}
-	tc.breakpoints = breakpoints
-	tc.lineWidths = lineWidths
-	tc.adjs = adjs
+	tc.setLines(lines)
 	return tc, nil
 }
 
@@ -217,32 +430,125 @@ func NewController(src FormatText, lineWidth float64, f FontFamily, cfg Controll
 // each call to DrawText encompasses a c.BeginText/EndText pair.) The returned bool indicates whether the Controller's
 // buffer still contains additional source text. If this value is true, then future calls to DrawText can be used to
 // draw the remaining source text - usually to different areas or ContentStreams. Changes made to c's
-// stroking color, nonstroking color, or render mode by the Controller will continue to affect operations on c
+// stroking color, nonstroking color, font, or render mode by the Controller will continue to affect operations on c
 // after DrawText returns, unless they are manually reverted.
 func (tc *Controller) DrawText(c *gdf.ContentStream, area gdf.Rect) (gdf.Point, bool, error) {
-	if gdf.PtToFU(area.Width(), tc.fontSize) < tc.lineWidth {
-		// very slight differences due to floating point precision errors should be ok
-		const epsilon = 1e-8
-		if tc.lineWidth-gdf.PtToFU(area.Width(), tc.fontSize) > epsilon {
-			return *new(gdf.Point), false, ErrWidth
-		}
+	if err := tc.check(area); err != nil {
+		return *new(gdf.Point), false, err
+	}
+	maxLines := tc.fit(area.Height())
+	if maxLines == tc.ln {
+		return *new(gdf.Point), false, ErrHeight
+	}
+	endPt, err := tc.drawLines(c, area, maxLines, tc.place(maxLines, area.Height()))
+	if err != nil {
+		return *new(gdf.Point), false, err
+	}
+	return endPt, !tc.IsDone, nil
+}
 
+// very slight differences due to floating point precision errors should be ok
+const epsilon = 1e-8
+
+// check returns an error if tc cannot draw any text to area.
+func (tc *Controller) check(area gdf.Rect) error {
+	if tc.lineWidth-area.Width() > epsilon {
+		return ErrWidth
 	}
-	if tc.n >= len(tc.tokens) {
-		return *new(gdf.Point), false, ErrEmpty
+	if tc.ln >= len(tc.lines) {
+		return ErrEmpty
 	}
-	if tc.leading <= 0 {
-		return *new(gdf.Point), false, ErrLeading
+	if tc.lineHeight <= 0 {
+		return ErrLeading
 	}
-	maxLines := int(area.Height()/tc.leading) + tc.ln
-	if maxLines < 1 {
-		return *new(gdf.Point), false, ErrHeight
+	return nil
+}
+
+// fit returns the index of the line that follows the last of tc's undrawn lines that DrawText draws to an area of the given
+// height: those that fit in it, less any lines that the styles of their paragraphs keep with the lines that do not.
+func (tc *Controller) fit(height float64) int {
+	end := tc.maxFit(height)
+	if k := tc.keep(end); k > tc.ln {
+		return k
 	}
-	if c.Leading != tc.leading {
-		c.SetLeading(tc.leading)
+	return end
+}
+
+// maxFit returns the index of the line that follows the last of tc's undrawn lines that fit in the given height.
+func (tc *Controller) maxFit(height float64) int {
+	n := tc.ln
+	for bottom := 0.0; n < len(tc.lines); n++ {
+		ln := tc.lines[n]
+		if n > tc.ln {
+			bottom += ln.before
+		}
+		b := tc.snap(bottom+ln.ascent) + ln.descent
+		if tc.snap(b) > height+epsilon {
+			break
+		}
+		bottom = b
 	}
-	if c.Font != tc.curFont || c.FontSize != tc.fontSize {
-		c.SetFont(tc.fontSize, tc.curFont)
+	return n
+}
+
+// Height returns the combined height, in points, of tc's undrawn lines.
+func (tc *Controller) Height() float64 {
+	return tc.linesHeight(tc.ln, len(tc.lines))
+}
+
+// FitHeight returns the combined height, in points, of those of tc's undrawn lines that fit in the given height. These are
+// the lines that DrawText draws to an area of that height.
+func (tc *Controller) FitHeight(height float64) float64 {
+	return tc.linesHeight(tc.ln, tc.fit(height))
+}
+
+// Width returns the width, in points, of the widest of tc's lines, including its indents. If tc's lineWidth is at least as
+// great as the natural width of each of its paragraphs, this is the narrowest lineWidth at which none of them is broken.
+func (tc *Controller) Width() float64 {
+	var w float64
+	for _, ln := range tc.lines {
+		left, right := tc.lineMargins(ln)
+		w = max(w, left+ln.width+right)
+	}
+	return w
+}
+
+// MinWidth returns the width, in points, of the widest run of tc's text that cannot be broken across lines, including the
+// indents of the line on which it is set. It is the narrowest lineWidth at which tc's text could be set without overflowing.
+func (tc *Controller) MinWidth() float64 {
+	var w, run float64
+	var p int // the index of the paragraph
+	indents := func() float64 { return tc.indents(p) + max(tc.styles[p].FirstIndent, 0) }
+	for _, t := range tc.tokens {
+		switch v := t.(type) {
+		case box, inlineBox, boundSkip:
+			run += v.Width()
+			w = max(w, run+indents())
+		case hyphen:
+			// the hyphen is drawn at the end of the run when the line is broken at it
+			w = max(w, run+v.Width()+indents())
+			run = 0
+		case newline:
+			p++
+			run = 0
+		default:
+			run = 0
+		}
+	}
+	return w
+}
+
+// linesHeight returns the combined height of tc's lines from line i up to line j.
+func (tc *Controller) linesHeight(i, j int) float64 {
+	_, h := tc.baselines(i, j)
+	return h
+}
+
+// drawLines draws tc's lines, from the next undrawn line up to line numLines, to area of c, with their baselines offs points
+// below the top of the area, and returns the position of the text cursor at the start of the line that would follow them.
+func (tc *Controller) drawLines(c *gdf.ContentStream, area gdf.Rect, numLines int, offs []float64) (gdf.Point, error) {
+	if tc.leading > 0 && c.Leading != tc.leading {
+		c.SetLeading(tc.leading)
 	}
 	if !cmpColor(c.NColor, tc.ncolor) && tc.ncolor != nil {
 		c.SetColor(tc.ncolor)
@@ -250,22 +556,38 @@ func (tc *Controller) DrawText(c *gdf.ContentStream, area gdf.Rect) (gdf.Point,
 	if !cmpColor(c.SColor, tc.scolor) && tc.scolor != nil {
 		c.SetColorStroke(tc.scolor)
 	}
+	// highlights are drawn behind the text and decorations in front of it, outside of the text object
+	var highlights, decorations []fill
+	if tc.decorated {
+		highlights, decorations = tc.decorate(area, numLines, offs)
+		drawFills(c, highlights)
+	}
+	objs := tc.inlines(area, numLines, offs)
+	links := tc.links(area, numLines, offs)
 	et, err := c.BeginText()
+	if err != nil {
+		return *new(gdf.Point), err
+	}
 	if c.RenderMode != tc.renderMode {
 		c.SetRenderMode(tc.renderMode)
 	}
-	c.SetTextOffset(area.LLX, area.URY-tc.leading)
-	if err != nil {
-		return *new(gdf.Point), false, err
+	c.SetTextOffset(area.LLX, area.URY)
+	tc.writeLines(c, numLines, offs)
+	last, next := tc.lines[tc.ln-1], tc.lines[tc.ln-1]
+	if tc.ln < len(tc.lines) {
+		next = tc.lines[tc.ln]
 	}
-	tc.writeLines(c, maxLines)
-	endPt := c.RawTextCursor()
-	err = et()
-	if err != nil {
-		return *new(gdf.Point), false, err
+	// the following line is positioned as it would be if it were drawn to the same area
+	base := offs[len(offs)-1]
+	endPt := gdf.Transform(gdf.Point{X: 0, Y: base - tc.snap(base+last.descent+next.before+next.ascent)}, c.LineMatrix)
+	if err = et(); err != nil {
+		return *new(gdf.Point), err
 	}
-	tc.IsDone = tc.n == len(tc.tokens)-1
-	return endPt, tc.n == len(tc.tokens)-1, nil
+	drawInlines(c, objs)
+	drawFills(c, decorations)
+	tc.addLinks(c, links)
+	tc.IsDone = tc.ln == len(tc.lines)
+	return endPt, nil
 }
 
 type token interface {
@@ -273,21 +595,20 @@ type token interface {
 }
 
 type box struct {
-	chars []rune
-	advs  []int
-	kerns []int
-	width float64
+	chars  []rune
+	glyphs []gdf.Glyph
+	width  float64
 }
 
 func (b box) Width() float64 { return b.width }
 
-func width(advs, kerns []int) float64 {
-	var out int
-	_ = kerns[len(advs)-1]
-	for i := 0; i < len(advs); i++ {
-		out += advs[i] + kerns[i]
+// newBox returns a box containing glyphs, which are set at the given font size.
+func newBox(chars []rune, glyphs []gdf.Glyph, fontSize float64) box {
+	var w int
+	for _, g := range glyphs {
+		w += g.Adv
 	}
-	return float64(out)
+	return box{chars: slices.Clone(chars), glyphs: glyphs, width: gdf.FUToPt(float64(w), fontSize)}
 }
 
 type skip float64
@@ -298,172 +619,234 @@ type newline struct{}
 
 func (n newline) Width() float64 { return 0 }
 
-type fWeight uint
+// the start of a paragraph whose first line is indented, or which is marked as a list item
+type flIndent struct {
+	marker *lineItem // the list marker, if any
+}
 
-func (f fWeight) Width() float64 { return 0 }
+func (f flIndent) Width() float64 { return 0 }
 
-const (
-	regular fWeight = iota
-	bold
-	ital
-	boldItal
-)
+type hyphen float64
 
-type flIndent float64
+func (h hyphen) Width() float64 { return float64(h) }
 
-func (f flIndent) Width() float64 { return 0 }
+// a space at which a line cannot be broken, e.g., one that precedes a closing punctuation mark
+type boundSkip float64
 
-// nonstroking color change
-type ncChange struct {
-	r, g, b uint
-}
+func (b boundSkip) Width() float64 { return float64(b) }
 
-func (n ncChange) Width() float64 { return 0 }
+// a break opportunity between two boxes; its value is the cost of breaking the line there, as a multiple of the space advance
+type penalty float64
 
-type hyphen float64
+func (p penalty) Width() float64 { return 0 }
 
-func (h hyphen) Width() float64 { return float64(h) }
+// A tokInfo holds the bidi embedding level of a token, that of the paragraph to which the token belongs, and the index
+// of the span from which the token was taken.
+type tokInfo struct {
+	level, para uint8
+	span        int
+}
 
-// parses the source text and returns a slice of raw tokens
-func (tc *Controller) tokenize(src FormatText) []token {
-	// make local copies of these values so future calls to DrawText are not affected by operations here that nonetheless depend on future text states
-	curFont := tc.curFont
-	isBold := tc.isBold
-	isItal := tc.isItal
+// spaceAdv returns the advance, in points, of a space in the font and size of tc.spans[span].
+func (tc *Controller) spaceAdv(span int) float64 {
+	s := tc.spans[span]
+	return gdf.FUToPt(float64(s.Font.GlyphAdvance(' ')), s.Size)
+}
 
-	src = append(src, []rune{'\n', eot_tok}...) // this simplifies some of the logic
-	out := make([]token, 0, len(src))
-	run := []rune{}
-	kerns := []int{}
-	advs := []int{}
-	i := 0
-	for ; ; i++ {
-		switch src[i] {
-		case '\n', '\r':
-			if len(run) != 0 {
-				chars := make([]rune, len(run))
-				copy(chars, run)
-				kerns2 := make([]int, len(run))
-				copy(kerns2, kerns)
-				advs2 := make([]int, len(run))
-				copy(advs2, advs)
-				out = append(out, box{chars: chars, kerns: kerns2, advs: advs2, width: width(advs2, kerns2)})
-			}
-			run = run[:0]
-			advs = advs[:0]
-			kerns = kerns[:0]
+// extent returns the distances, in points, by which the line box of text drawn in the style of s extends above and below the
+// baseline. As in CSS, the difference between the height of the line box and the sum of the font's ascent and descent is
+// divided equally between the top and the bottom of the box.
+func (tc *Controller) extent(s Span) (above, below float64) {
+	asc := gdf.FUToPt(float64(s.Font.Ascent), s.Size)
+	desc := gdf.FUToPt(float64(s.Font.Descent), s.Size)
+	halfLeading := (tc.lineHeight - 1) * (asc + desc) / 2
+	return asc + halfLeading + s.Rise, desc + halfLeading - s.Rise
+}
 
-			// finishing glue
-			out = append(out, skip(0))
-			out = append(out, newline{})
-			if tc.firstIndent != 0 {
-				out = append(out, flIndent(gdf.FUToPt(tc.firstIndent, tc.fontSize)))
-			}
-		case '\u0020':
-			if len(run) != 0 {
-				chars := make([]rune, len(run))
-				copy(chars, run)
-				kerns2 := make([]int, len(run))
-				copy(kerns2, kerns)
-				advs2 := make([]int, len(run))
-				copy(advs2, advs)
-				out = append(out, box{chars: chars, kerns: kerns2, advs: advs2, width: width(advs2, kerns2)})
-			}
-			run = run[:0]
-			advs = advs[:0]
-			kerns = kerns[:0]
-			adv := curFont.GlyphAdvance('\u0020')
-			out = append(out, skip(adv))
-		case col_tok:
-			if len(run) != 0 {
-				chars := make([]rune, len(run))
-				copy(chars, run)
-				kerns2 := make([]int, len(run))
-				copy(kerns2, kerns)
-				advs2 := make([]int, len(run))
-				copy(advs2, advs)
-				out = append(out, box{chars: chars, kerns: kerns2, advs: advs2, width: width(advs2, kerns2)})
+// hyphenate removes any soft hyphens from the word t, and returns the resulting text and the indices of the runes before
+// which it can be hyphenated. If t contains soft hyphens, it can only be hyphenated at those points. Otherwise, each
+// sequence of letters in t is hyphenated according to the patterns of h, unless h is nil or t is right-to-left.
+func (tc *Controller) hyphenate(t []rune, rtl bool, h *Hyphenator) ([]rune, []int) {
+	if slices.Contains(t, '\u00AD') {
+		var text []rune
+		var points []int
+		for _, r := range t {
+			if r != '\u00AD' {
+				text = append(text, r)
+			} else if len(text) != 0 && (len(points) == 0 || points[len(points)-1] != len(text)) {
+				points = append(points, len(text))
 			}
-			run = run[:0]
-			advs = advs[:0]
-			kerns = kerns[:0]
+		}
+		if len(points) != 0 && points[len(points)-1] == len(text) {
+			points = points[:len(points)-1]
+		}
+		return text, points
+	}
+	if h == nil || rtl {
+		return t, nil
+	}
+	var points []int
+	for i := 0; i < len(t); {
+		if !unicode.IsLetter(t[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(t) && (unicode.IsLetter(t[j]) || unicode.Is(unicode.Mn, t[j])) {
+			j++
+		}
+		for _, p := range h.Hyphenate(t[i:j], tc.minLeft, tc.minRight) {
+			points = append(points, i+p)
+		}
+		i = j
+	}
+	return t, points
+}
+
+// resolveBidi returns the bidi embedding level of each rune of src, along with the embedding level of the paragraph to which
+// each rune belongs. Paragraphs are separated by mandatory line breaks.
+func (tc *Controller) resolveBidi(src []rune) (levels, paras []uint8) {
+	classes := bidiClasses(src)
+	levels = make([]uint8, 0, len(src))
+	paras = make([]uint8, 0, len(src))
+	for start := 0; start < len(src); {
+		end := start
+		for end < len(src) && !isNewline(src[end]) {
+			end++
+		}
+		end = min(end+1, len(src)) // include the paragraph separator
+		l, para := bidiLevels(src[start:end], classes[start:end], tc.direction)
+		levels = append(levels, l...)
+		for range l {
+			paras = append(paras, para)
+		}
+		start = end
+	}
+	return levels, paras
+}
 
-			if i > len(src)-10 {
+// tokenize parses the text of tc's spans and returns a slice of raw tokens, along with their bidi embedding levels and spans.
+func (tc *Controller) tokenize() ([]token, []tokInfo, error) {
+	var src []rune
+	var spanOf []int // the index of the span to which each rune of src belongs
+	for i, s := range tc.spans {
+		if s.Inline != nil {
+			src = append(src, objectReplacement)
+			spanOf = append(spanOf, i)
+			continue
+		}
+		for _, r := range s.Text {
+			src = append(src, r)
+			spanOf = append(spanOf, i)
+		}
+	}
+	// this simplifies some of the logic
+	src = append(src, '\n')
+	spanOf = append(spanOf, len(tc.spans)-1)
+
+	levels, paras := tc.resolveBidi(src)
+	costs := breakCosts(src)
+	out := make([]token, 0, len(src))
+	info := make([]tokInfo, 0, len(src))
+	add := func(t token, ti tokInfo) {
+		out = append(out, t)
+		info = append(info, ti)
+	}
+	// marks the start of the paragraph that begins at src[i]
+	paraStart := func(i int) error {
+		tc.styles = append(tc.styles, tc.paraStyle(spanOf[i]))
+		if tc.styles[len(tc.styles)-1].FirstIndent == 0 && tc.list == nil {
+			return nil
+		}
+		ti := tokInfo{level: paras[i], para: paras[i], span: spanOf[i]}
+		marker, err := tc.marker(src, i, ti.para, ti.span)
+		if err != nil {
+			return err
+		}
+		add(flIndent{marker: marker}, ti)
+		return nil
+	}
+	if err := paraStart(0); err != nil {
+		return nil, nil, err
+	}
+	run := []rune{}
+	var runInfo tokInfo
+	// shapes the current run, if any, and appends the resulting box to out
+	flush := func() error {
+		if len(run) == 0 {
+			return nil
+		}
+		s := tc.spans[runInfo.span]
+		rtl := runInfo.level%2 == 1
+		text, points := tc.hyphenate(run, rtl, tc.hyphs[runInfo.span])
+		glyphs, err := s.Font.ShapeDirection(text, rtl, tc.features)
+		if err != nil {
+			return err
+		}
+		// the run is shaped as a whole, and then divided at the hyphenation points that fall between clusters
+		var start, chStart int
+		for _, p := range points {
+			k := slices.IndexFunc(glyphs, func(g gdf.Glyph) bool { return g.Cluster >= p })
+			if k <= start || glyphs[k].Cluster != p {
 				continue
 			}
-			var r, g, b uint
-			_, err := fmt.Sscanf(string(src[i+1:i+1+9+2]), "%03d,%03d,%03d", &r, &g, &b)
-			if err != nil {
-				continue
+			add(newBox(text[chStart:p], glyphs[start:k], s.Size), runInfo)
+			add(hyphen(gdf.FUToPt(float64(s.Font.GlyphAdvance('-')), s.Size)), runInfo)
+			start, chStart = k, p
+		}
+		add(newBox(text[chStart:], glyphs[start:], s.Size), runInfo)
+		run = run[:0]
+		return nil
+	}
+	for i, r := range src {
+		ti := tokInfo{level: levels[i], para: paras[i], span: spanOf[i]}
+		// runs of different directions or spans are shaped separately
+		if isNewline(r) || r == ' ' || r == '\t' || tc.spans[ti.span].Inline != nil || (len(run) != 0 && ti != runInfo) {
+			if err := flush(); err != nil {
+				return nil, nil, err
 			}
-			out = append(out, ncChange{r: r, g: g, b: b})
-			i += 11
-		case bold_tok: // bold
-			if len(run) != 0 {
-				chars := make([]rune, len(run))
-				copy(chars, run)
-				kerns2 := make([]int, len(run))
-				copy(kerns2, kerns)
-				advs2 := make([]int, len(run))
-				copy(advs2, advs)
-				out = append(out, box{chars: chars, kerns: kerns2, advs: advs2, width: width(advs2, kerns2)})
+		}
+		switch {
+		case isNewline(r):
+			// finishing glue
+			add(skip(0), ti)
+			add(newline{}, ti)
+			if i+1 < len(src) {
+				if err := paraStart(i + 1); err != nil {
+					return nil, nil, err
+				}
 			}
-			run = run[:0]
-			advs = advs[:0]
-			kerns = kerns[:0]
-			if isBold && isItal {
-				curFont = tc.family.Ital
-				out = append(out, ital)
-			} else if isBold {
-				curFont = tc.family.Regular
-				out = append(out, regular)
-			} else if isItal {
-				curFont = tc.family.BoldItal
-				out = append(out, boldItal)
-			} else {
-				curFont = tc.family.Bold
-				out = append(out, bold)
+		case tc.spans[ti.span].Inline != nil:
+			add(inlineBox{tc.spans[ti.span].Inline}, ti)
+			if costs[i] != noBreak {
+				add(penalty(costs[i]), ti)
 			}
-			isBold = !isBold
-		case ital_tok: // italic
-			if len(run) != 0 {
-				chars := make([]rune, len(run))
-				copy(chars, run)
-				kerns2 := make([]int, len(run))
-				copy(kerns2, kerns)
-				advs2 := make([]int, len(run))
-				copy(advs2, advs)
-				out = append(out, box{chars: chars, kerns: kerns2, advs: advs2, width: width(advs2, kerns2)})
+		case r == '\t':
+			add(tab{}, ti)
+			if costs[i] != noBreak {
+				add(penalty(costs[i]), ti)
 			}
-			run = run[:0]
-			advs = advs[:0]
-			kerns = kerns[:0]
-			if isItal && isBold {
-				curFont = tc.family.Bold
-				out = append(out, bold)
-			} else if isItal {
-				curFont = tc.family.Regular
-				out = append(out, regular)
-			} else if isBold {
-				curFont = tc.family.BoldItal
-				out = append(out, boldItal)
+		case r == ' ':
+			if costs[i] == noBreak {
+				add(boundSkip(tc.spaceAdv(ti.span)), ti)
 			} else {
-				curFont = tc.family.Ital
-				out = append(out, ital)
-			}
-			isItal = !isItal
-		case eot_tok: // eot
-			if len(run) != 0 {
-				out = append(out, box{chars: run, kerns: kerns, advs: advs, width: width(advs, kerns)})
+				add(skip(tc.spaceAdv(ti.span)), ti)
 			}
-			return out
 		default:
-			run = append(run, src[i])
-			adv, kern := curFont.ShapedGlyphAdv(src[i], src[i+1])
-			kerns = append(kerns, kern)
-			advs = append(advs, adv)
+			if len(run) == 0 {
+				runInfo = ti
+			}
+			run = append(run, r)
+			// soft hyphens are handled when the run is shaped
+			if costs[i] != noBreak && r != '\u00AD' {
+				if err := flush(); err != nil {
+					return nil, nil, err
+				}
+				add(penalty(costs[i]), ti)
+			}
 		}
 	}
+	return out, info, nil
 }
 
 type node struct {
@@ -475,6 +858,9 @@ type node struct {
 	bestLW    float64 // width of a line starting at at activeNodes[bestStart] and terminating at the current node
 	bestR     float64 // adjustment ratio of a line starting at activeNodes[bestStart] and terminating at the current node
 	dSum      float64 // sum of the demerits for all nodes in the optimal path leading to and including the current node
+	hyphens   int     // number of consecutive hyphenated lines in the optimal path leading to and including the current node
+	y         float64 // distance from the top of the text to the bottom of the last line of the optimal path, if tc has LineBounds
+	bestPos   linePos // position of a line starting at activeNodes[bestStart] and terminating at the current node
 }
 
 // The algorithm used here - a modified version of the Knuth-Plass line-breaking algorithm - has O(n²) time complexity, but the value of n is
@@ -483,51 +869,82 @@ type node struct {
 // Alternative algorithms either cannot be adopted to text that includes optional hyphenated breaks and/or negative glyph advances, or find
 // potentially suboptimal line fits.
 // TODO: gracefully handle pathological cases.
-// breakpoints, lineWidths, adjs, err
-func (tc *Controller) breakLines(squishTolerance, stretchTolerance float64) (breakpoints []int, lineWidths []float64, adjs []float64, err error) {
-	curFont := tc.curFont
-
-	breakIndices := []int{}
-	lineLengths := []float64{}
-	//  adjs := []float64{}
+// The tolerances of each paragraph are relaxed by each attempt n to break the lines; see tolerances.
+func (tc *Controller) breakLines(n int) ([]line, error) {
+	lines := []line{}
 	activeNodes := []node{{
 		tIndex:    0,
 		pWidth:    0,
 		pSpaces:   0,
 		bestStart: -1,
 	}}
+	lead := tc.leadWidths()
 
 	var numSpaces float64
 	var lineStart int
-	curWidth := tc.firstIndent
+	var p int // the index of the current paragraph
+	style := tc.styles[p]
+	squishTolerance, stretchTolerance := tc.tolerances(p, n)
+	curWidth := style.FirstIndent
 	var runWidth float64
-	for i := 0; i < len(tc.tokens); i++ {
-		switch v := tc.tokens[i].(type) {
-		case flIndent:
-			// TODO
-		case box:
-			curWidth += v.Width()
-			runWidth += v.Width()
-			if runWidth > tc.lineWidth {
-				return nil, nil, nil, fmt.Errorf("%w: %s", ErrWordSize, string(v.chars))
-			}
-		case skip:
-			runWidth = 0
-			curWidth += v.Width()
-			numSpaces++
-
+	tabbed := false // whether the current paragraph contains tabs
+	// the width available to the text of a line that is as wide as tc's lineWidth
+	avail := tc.lineWidth - tc.indents(p)
+	// whether the current run of unbreakable tokens must be set on the paragraph's first line, after its first indent, i.e.,
+	// whether it is the first run of the paragraph
+	first := true
+	// reports whether the current run of unbreakable tokens fits on a line
+	fits := func() bool {
+		if first {
+			return runWidth <= avail-style.FirstIndent+epsilon
+		}
+		return runWidth <= avail+epsilon
+	}
+	// considers a break at token i, where glue of width w is removed from the end of the line; cost is the penalty for
+	// breaking there, as a multiple of the space advance. It returns false if no line can end at or after token i.
+	tryBreak := func(i int, w, cost float64, hyphenated bool) bool {
+		// this has to be done on a token-by token basis because the font can change
+		spAdv := tc.spaceAdv(tc.info[i].span)
+		finishing := false
+		var glue float64 // the number of spaces removed from the end of the line
+		if _, ok := tc.tokens[i].(skip); ok {
+			glue = 1
+			finishing = w == 0 // we've hit finishing glue
+		}
+		// finds the best line ending at token i that starts at an active node with the given number of consecutive
+		// hyphenated lines, or at any active node if hyphens < 0
+		findBest := func(hyphens int) (node, bool) {
 			newNode := node{tIndex: i, pWidth: curWidth, pSpaces: numSpaces}
-			var bestStart int
-			var bestLW, bestR float64
-			bestDemerits := math.Inf(0)
 			bestSumDemerits := math.Inf(0)
-			// this has to be done on a token-by token basis because the font can change
-			spAdv := float64(curFont.GlyphAdvance('\u0020'))
 			// check if we have a feasible breakpoint
 			for j := lineStart; j < len(activeNodes); j++ {
-
-				r := (tc.lineWidth + activeNodes[j].pWidth - curWidth + v.Width()) / (numSpaces - activeNodes[j].pSpaces - 1)
-				if v.Width() == 0 { // we've hit finishing glue
+				lw := curWidth - activeNodes[j].pWidth - w
+				// the line begins at token start, and indent is its first indent
+				start, indent := activeNodes[j].tIndex+1, 0.0
+				if activeNodes[j].bestStart == -1 {
+					start, indent = activeNodes[j].tIndex, style.FirstIndent
+				}
+				if tabbed {
+					// the widths of the tabs depend on where the line starts
+					lw = tc.expandTabs(start, i, indent, false)
+				}
+				// lines without any spaces cannot be squeezed, and can be stretched as if they had one
+				spaces := numSpaces - activeNodes[j].pSpaces - glue
+				var before float64
+				if activeNodes[j].bestStart == -1 && p > 0 {
+					before = style.SpaceBefore + tc.styles[p-1].SpaceAfter
+				}
+				pos, lineAvail := tc.slot(activeNodes[j].y, before, indent+lead[start], start, i, p)
+				slack := lineAvail - lw
+				if slack < 0 && slack > -epsilon {
+					// e.g., a line that ends at a tab stop at the end of the line
+					slack = 0
+				}
+				r := slack / max(spaces, 1)
+				if spaces < 1 && r < 0 {
+					r = math.Inf(-1)
+				}
+				if finishing {
 					if r > 0 || math.IsNaN(r) {
 						r = 0
 						lineStart = j + 1 // disable nodes that don't terminate here
@@ -541,49 +958,92 @@ func (tc *Controller) breakLines(squishTolerance, stretchTolerance float64) (bre
 				if r < -spAdv*squishTolerance {
 					lineStart = j + 1
 				}
+				if hyphens >= 0 && activeNodes[j].hyphens != hyphens {
+					continue
+				}
 
 				if r >= -spAdv*squishTolerance && r <= spAdv*stretchTolerance {
-					demerits := r * r
+					demerits := r*r + (cost*spAdv)*(cost*spAdv)
 					if demerits+activeNodes[j].dSum < bestSumDemerits {
 						bestSumDemerits = demerits + activeNodes[j].dSum
-						bestDemerits = demerits
-						bestStart = activeNodes[j].nIndex
-						bestLW = curWidth - activeNodes[j].pWidth - v.Width()
-						bestR = r
+						newNode.bestStart = activeNodes[j].nIndex
+						newNode.bestLW = lw
+						newNode.bestR = r
+						newNode.hyphens = activeNodes[j].hyphens
+						newNode.y = pos.bottom
+						newNode.bestPos = pos
 					}
 				}
 			}
+			newNode.dSum = bestSumDemerits
+			return newNode, !math.IsInf(bestSumDemerits, 0)
+		}
 
-			if !math.IsInf(bestDemerits, 0) {
-				newNode.dSum = bestSumDemerits
-				newNode.bestStart = bestStart
-				newNode.bestLW = bestLW
-				newNode.bestR = bestR
-				newNode.nIndex = len(activeNodes)
-				activeNodes = append(activeNodes, newNode)
+		if hyphenated && tc.maxHyphens > 0 {
+			// a separate node is kept for each number of consecutive hyphenated lines that can end here
+			for h := 0; h < tc.maxHyphens; h++ {
+				if n, ok := findBest(h); ok {
+					n.hyphens = h + 1
+					n.nIndex = len(activeNodes)
+					activeNodes = append(activeNodes, n)
+				}
 			}
-			// unable to proceed
-			if lineStart == len(activeNodes) {
-				return nil, nil, nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
+		} else if n, ok := findBest(-1); ok {
+			if hyphenated {
+				n.hyphens++
+			} else {
+				n.hyphens = 0
 			}
-		case fWeight:
-			switch v {
-			case regular:
-				curFont = tc.family.Regular
-			case bold:
-				curFont = tc.family.Bold
-			case ital:
-				curFont = tc.family.Ital
-			case boldItal:
-				curFont = tc.family.BoldItal
+			n.nIndex = len(activeNodes)
+			activeNodes = append(activeNodes, n)
+		}
+		// unable to proceed
+		return lineStart != len(activeNodes)
+	}
+	for i := 0; i < len(tc.tokens); i++ {
+		switch v := tc.tokens[i].(type) {
+		case tab:
+			tabbed = true
+			runWidth, first = 0, first && runWidth == 0
+		case box:
+			curWidth += v.Width()
+			runWidth += v.Width()
+			if !fits() {
+				return nil, fmt.Errorf("%w: %s", ErrWordSize, string(v.chars))
+			}
+		case inlineBox:
+			curWidth += v.Width()
+			runWidth += v.Width()
+			if !fits() {
+				return nil, fmt.Errorf("%w: inline object", ErrWordSize)
+			}
+		case boundSkip:
+			curWidth += v.Width()
+			runWidth += v.Width()
+			numSpaces++
+		case skip:
+			runWidth, first = 0, first && runWidth == 0
+			curWidth += v.Width()
+			numSpaces++
+			if !tryBreak(i, v.Width(), 0, false) {
+				return nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
+			}
+		case penalty:
+			runWidth, first = 0, first && runWidth == 0
+			if !tryBreak(i, 0, float64(v), false) {
+				return nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
 			}
-		case ncChange:
 		case hyphen:
+			// the hyphen is only drawn if the line is broken here
+			runWidth, first = 0, first && runWidth == 0
+			if !tryBreak(i, -v.Width(), hyphenBreak, true) {
+				return nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
+			}
 		case newline:
 			runWidth = 0
 			// this should already have been caught, but it might help to do a bounds check just to be safe
 			if len(activeNodes) == 0 {
-				return nil, nil, nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
+				return nil, fmt.Errorf("%w, squish: %f stretch: %f", ErrTolerance, squishTolerance, stretchTolerance)
 			}
 			// the remaining active node should be the one with the optimal endpoint
 			endNode := activeNodes[len(activeNodes)-1]
@@ -597,165 +1057,358 @@ func (tc *Controller) breakLines(squishTolerance, stretchTolerance float64) (bre
 			slices.Reverse(nodes)
 
 			for _, n := range nodes {
-				breakIndices = append(breakIndices, n.tIndex)
-				lineLengths = append(lineLengths, n.bestLW)
-				adjs = append(adjs, n.bestR)
+				ln := line{end: n.tIndex, width: n.bestLW, adj: n.bestR, skip: n.bestPos.skip, paragraph: p}
+				ln.offset, ln.measure = n.bestPos.offset, n.bestPos.measure
+				if style.Justification == Ragged {
+					ln.adj = 0
+				}
+				lines = append(lines, ln)
 			}
 			activeNodes = []node{{
 				tIndex:    i,
 				pWidth:    0,
 				pSpaces:   0,
 				bestStart: -1,
+				y:         endNode.y,
 			}}
 
-			curWidth = tc.firstIndent
+			if p+1 < len(tc.styles) {
+				p++
+				style = tc.styles[p]
+				squishTolerance, stretchTolerance = tc.tolerances(p, n)
+				avail = tc.lineWidth - tc.indents(p)
+			}
+			curWidth = style.FirstIndent
+			first = true
 			numSpaces = 0
 			lineStart = 0
+			tabbed = false
 		}
 	}
-	if tc.just == Ragged {
-		adjs = make([]float64, len(adjs))
-	}
-	return breakIndices, lineLengths, adjs, nil
+	return lines, nil
 }
 
-// the run needs to be considered in absence of the formatting directives, but then it needs to be reconstituted with those
-// directives in mind
-func (tc *Controller) writeLines(c *gdf.ContentStream, numLines int) {
-	lineCount := tc.ln
-	breaks := map[int]struct{}{}
-	for _, ind := range tc.breakpoints {
-		breaks[ind] = struct{}{}
-	}
+// A line is a sequence of tokens that is drawn as a single line of text.
+type line struct {
+	start, end int       // the line consists of tc.tokens[start:end], and is broken at tc.tokens[end]
+	width      float64   // natural width, in points, including any indent
+	adj        float64   // adjustment, in points, to each of the spaces ('\x20') in the line
+	ascent     float64   // distance from the top of the line to its baseline
+	descent    float64   // distance from the baseline of the line to its bottom
+	para       uint8     // embedding level of the paragraph to which the line belongs
+	indented   bool      // whether the line is the first line of an indented paragraph
+	marker     *lineItem // the list marker drawn before the line, if any
+	last       bool      // whether the line is the last line of its paragraph
+	tabbed     bool      // whether the line contains tabs
+	skip       float64   // the space, in points, that is left empty above the line because its bounds were too narrow
+	paragraph  int       // the index of the paragraph to which the line belongs
+	before     float64   // the space, in points, between the line and the line above it, beyond their ascent and descent
+	inline     bool      // whether the line contains inline objects
+	offset     float64   // the distance, in points, between the left edge of the area in which the line is drawn and its bounds
+	measure    float64   // the width, in points, of the line's bounds
+}
 
-	run := []rune{}
-	kerns := []int{}
-	indented := tc.firstIndent != 0 && tc.n == 0
-	if indented {
-		c.Concat(gdf.Translate(gdf.FUToPt(tc.firstIndent, tc.fontSize), 0))
-	}
-	i := tc.n
-	for ; i < len(tc.tokens) && lineCount < len(tc.breakpoints); i++ {
-		if _, ok := breaks[i]; ok {
-			var dif, alignAdj float64
-			if tc.adjs[lineCount] == 0 && (tc.alignment == Center || tc.alignment == Right) && len(run) != 0 {
-				dif = tc.lineWidth - tc.lineWidths[lineCount]
-				switch tc.alignment {
-				case Center:
-					alignAdj = dif / 2
-				case Right:
-					alignAdj = dif
-				}
-				c.Concat(gdf.Translate(gdf.FUToPt(alignAdj, tc.fontSize), 0))
-			}
-			if len(run) != 0 {
-				if tc.adjs[lineCount] != 0 {
-					c.SetWordSpace(gdf.FUToPt(tc.adjs[lineCount], c.FontSize))
-					c.ShowText(run, kerns)
-					c.SetWordSpace(0)
-				} else {
-					c.ShowText(run, kerns)
-				}
+// setLines sets tc's lines to lines, whose end and width fields have been set by breakLines, after filling in the rest of
+// their fields.
+func (tc *Controller) setLines(lines []line) {
+	start := 0
+	for k := range lines {
+		ln := &lines[k]
+		ln.start = start
+		ln.para = tc.info[ln.end].para
+		style := tc.style(*ln)
+		if k == 0 || lines[k-1].last {
+			ln.before = style.SpaceBefore
+			if k > 0 {
+				ln.before += tc.style(lines[k-1]).SpaceAfter
 			}
-			if dif != 0 {
-				c.Concat(gdf.Translate(-gdf.FUToPt(alignAdj, tc.fontSize), 0))
-			}
-			c.NextLine()
-			if indented {
-				c.Concat(gdf.Translate(-gdf.FUToPt(tc.firstIndent, tc.fontSize), 0))
-				indented = false
-			}
-			lineCount++
-			run = run[:0]
-			kerns = kerns[:0]
-			if lineCount == numLines {
-				tc.n = i + 1
-				tc.ln = lineCount
-				return
+		}
+		// the last line of each paragraph is broken at its finishing glue
+		if ln.end+1 < len(tc.tokens) {
+			_, ln.last = tc.tokens[ln.end+1].(newline)
+		}
+		if j := slices.IndexFunc(tc.tokens[start:ln.end], func(t token) bool {
+			_, ok := t.(flIndent)
+			return ok
+		}); j >= 0 {
+			ln.indented = style.FirstIndent != 0
+			ln.marker = tc.tokens[start+j].(flIndent).marker
+		}
+		ln.tabbed = slices.ContainsFunc(tc.tokens[start:ln.end], func(t token) bool {
+			_, ok := t.(tab)
+			return ok
+		})
+		if ln.tabbed {
+			var indent float64
+			if ln.indented {
+				indent = style.FirstIndent
 			}
-			continue
+			ln.width = tc.expandTabs(start, ln.end, indent, true)
+			ln.adj = 0
 		}
+		ln.ascent, ln.descent, ln.inline = tc.lineExtent(start, ln.end)
+		// the space that is skipped above the line is treated as part of it
+		ln.ascent += ln.skip
+		start = ln.end + 1
+	}
+	tc.lines = lines
+}
+
+func (l line) height() float64 { return l.ascent + l.descent }
+
+// lineExtent returns the ascent and descent of the line that consists of tc.tokens[start:end] and is broken at tc.tokens[end],
+// which are those of its tallest text, and whether it contains inline objects.
+func (tc *Controller) lineExtent(start, end int) (ascent, descent float64, inline bool) {
+	ascent, descent = tc.extent(tc.spans[tc.info[end].span])
+	for i := start; i < end; i++ {
 		switch v := tc.tokens[i].(type) {
 		case box:
-			run = append(run, v.chars...)
-			kerns = append(kerns, v.kerns...)
-		case fWeight:
-			if len(run) != 0 {
-				var dif, alignAdj float64
-				if tc.adjs[lineCount] == 0 && (tc.alignment == Center || tc.alignment == Right) && len(run) != 0 {
-					dif = tc.lineWidth - tc.lineWidths[lineCount]
-					switch tc.alignment {
-					case Center:
-						alignAdj = dif / 2
-					case Right:
-						alignAdj = dif
-					}
-					c.Concat(gdf.Translate(gdf.FUToPt(alignAdj, tc.fontSize), 0))
-				}
-				if tc.adjs[lineCount] != 0 {
-					c.SetWordSpace(gdf.FUToPt(tc.adjs[lineCount], c.FontSize))
-					c.ShowText(run, kerns)
-					c.SetWordSpace(0)
-				} else {
-					c.ShowText(run, kerns)
-				}
+			above, below := tc.extent(tc.spans[tc.info[i].span])
+			ascent, descent = max(ascent, above), max(descent, below)
+		case inlineBox:
+			rise := tc.spans[tc.info[i].span].Rise
+			ascent, descent = max(ascent, v.Height-v.Baseline+rise), max(descent, v.Baseline-rise)
+			inline = true
+		}
+	}
+	return ascent, descent, inline
+}
 
-				if dif != 0 {
-					c.Concat(gdf.Translate(-gdf.FUToPt(alignAdj, tc.fontSize), 0))
-				}
-			}
-			run = run[:0]
-			kerns = kerns[:0]
-			switch v {
-			case regular:
-				c.SetFont(tc.fontSize, tc.family.Regular)
-			case bold:
-				c.SetFont(tc.fontSize, tc.family.Bold)
-			case ital:
-				c.SetFont(tc.fontSize, tc.family.Ital)
-			case boldItal:
-				c.SetFont(tc.fontSize, tc.family.BoldItal)
-			}
-		case ncChange:
-			if len(run) != 0 {
-				var dif, alignAdj float64
-				if tc.adjs[lineCount] == 0 && (tc.alignment == Center || tc.alignment == Right) && len(run) != 0 {
-					dif = tc.lineWidth - tc.lineWidths[lineCount]
-					switch tc.alignment {
-					case Center:
-						alignAdj = dif / 2
-					case Right:
-						alignAdj = dif
-					}
-					c.Concat(gdf.Translate(gdf.FUToPt(alignAdj, tc.fontSize), 0))
-				}
-				if tc.adjs[lineCount] != 0 {
-					c.SetWordSpace(gdf.FUToPt(tc.adjs[lineCount], c.FontSize))
-					c.ShowText(run, kerns)
-					c.SetWordSpace(0)
-				} else {
-					c.ShowText(run, kerns)
-				}
+// A lineItem is a box, space, or inline object on a line, along with the span and bidi embedding level with which it is drawn.
+type lineItem struct {
+	glyphs []gdf.Glyph
+	span   int
+	level  uint8
+	space  bool
+	obj    *Inline // the inline object, if the item is one
+}
 
-				if dif != 0 {
-					c.Concat(gdf.Translate(-gdf.FUToPt(alignAdj, tc.fontSize), 0))
-				}
-			}
-			run = run[:0]
-			kerns = kerns[:0]
-			c.SetColor(gdf.RGBColor{R: float64(v.r) / 255, G: float64(v.g) / 255, B: float64(v.b) / 255})
-		case skip:
+// writeLines draws tc's lines, beginning with the next undrawn line and ending before line numLines, with their baselines
+// offs points below the top of the area.
+func (tc *Controller) writeLines(c *gdf.ContentStream, numLines int, offs []float64) {
+	var prev float64 // the first line is positioned relative to the top of the area
+	for k := 0; tc.ln < numLines; tc.ln, k = tc.ln+1, k+1 {
+		ln := tc.lines[tc.ln]
+		c.SetTextOffset(0, prev-offs[k])
+		tc.writeLine(c, tc.lineItems(ln), ln)
+		prev = offs[k]
+	}
+}
+
+// lineItems returns the items drawn on ln, in logical order.
+func (tc *Controller) lineItems(ln line) []lineItem {
+	items := []lineItem{}
+	for i := ln.start; i < ln.end; i++ {
+		ti := tc.info[i]
+		switch v := tc.tokens[i].(type) {
+		case box:
+			items = append(items, lineItem{glyphs: v.glyphs, span: ti.span, level: ti.level})
+		case inlineBox:
+			items = append(items, lineItem{span: ti.span, level: ti.level, obj: v.Inline})
+		case tab:
+			// tabs take the paragraph level (UAX #9, L1)
+			items = append(items, tabItem(v, tc.spans[ti.span], ti.span, ln.para))
+		case skip, boundSkip:
 			if v.Width() != 0 {
-				run = append(run, ' ')
-				kerns = append(kerns, 0)
+				sp := tc.spans[ti.span].Font.RuneGlyph(' ')
+				items = append(items, lineItem{glyphs: []gdf.Glyph{sp}, span: ti.span, level: ti.level, space: true})
 			}
+		}
+	}
+	// the hyphen is only drawn if the line is broken at it
+	if _, ok := tc.tokens[ln.end].(hyphen); ok {
+		ti := tc.info[ln.end]
+		items = append(items, lineItem{glyphs: []gdf.Glyph{tc.spans[ti.span].Font.RuneGlyph('-')}, span: ti.span, level: ti.level})
+	}
+	// trailing whitespace takes the paragraph level (UAX #9, L1)
+	for k := len(items) - 1; k >= 0 && items[k].space; k-- {
+		items[k].level = ln.para
+	}
+	return items
+}
 
-		case flIndent:
-			indented = true
-			c.Concat(gdf.Translate(float64(v), 0))
-		case hyphen:
+// arrange returns the offset, in points, of the start of ln from the left edge of the area in which it is drawn, along with
+// the indices of its items in visual order.
+func (tc *Controller) arrange(items []lineItem, ln line) (float64, []int) {
+	levels := make([]uint8, len(items))
+	for k := range items {
+		levels[k] = items[k].level
+	}
+
+	// the first line of an indented paragraph is indented from the paragraph's starting margin
+	style := tc.style(ln)
+	var indent float64
+	if ln.indented {
+		indent = style.FirstIndent
+	}
+	lead, trail := tc.lineMargins(ln)
+	if ln.para%2 == 0 {
+		lead += indent
+	} else {
+		trail += indent
+	}
+	if ln.adj != 0 {
+		return lead, visualOrder(levels)
+	}
+	width := ln.width - indent
+	align := style.Alignment
+	if ln.tabbed {
+		align = Start
+	}
+	var x float64
+	switch align.resolve(ln.para) {
+	case Left:
+		x = lead
+	case Right:
+		x = tc.lineWidth - trail - width
+	case Center:
+		x = lead + (tc.lineWidth-lead-trail-width)/2
+	}
+	return x, visualOrder(levels)
+}
+
+// margins returns the distances, in points, between the left and right edges of the area in which the lines of paragraph p,
+// whose embedding level is para, are drawn and the edges of the paragraph's text, not including any first-line indent.
+func (tc *Controller) margins(p int, para uint8) (left, right float64) {
+	left, right = tc.styles[p].LeftIndent, tc.styles[p].RightIndent
+	if para%2 == 0 {
+		left += tc.list.indent()
+	} else {
+		right += tc.list.indent()
+	}
+	return left, right
+}
+
+// lineMargins returns the distances, in points, between the left and right edges of the area in which ln is drawn and the
+// edges of its text, not including any first-line indent.
+func (tc *Controller) lineMargins(ln line) (left, right float64) {
+	left, right = tc.margins(ln.paragraph, ln.para)
+	return left + ln.offset, right + tc.lineWidth - ln.offset - ln.measure
+}
+
+// markerOffset returns the offset, in points, of the start of ln's list marker from the left edge of the area in which ln
+// is drawn. The marker ends one space before the paragraph's starting margin.
+func (tc *Controller) markerOffset(ln line) float64 {
+	left, right := tc.lineMargins(ln)
+	gap := tc.spaceAdv(ln.marker.span)
+	if ln.para%2 == 1 {
+		return tc.lineWidth - right + gap
+	}
+	return left - gap - tc.itemWidth(*ln.marker, ln)
+}
+
+// itemWidth returns the width, in points, of it when it is drawn on ln.
+func (tc *Controller) itemWidth(it lineItem, ln line) float64 {
+	if it.obj != nil {
+		return it.obj.Width
+	}
+	var w int
+	for _, g := range it.glyphs {
+		w += g.Adv
+	}
+	out := gdf.FUToPt(float64(w), tc.spans[it.span].Size)
+	if it.space {
+		out += ln.adj
+	}
+	return out
+}
+
+// writeLine draws the items of ln in visual order.
+func (tc *Controller) writeLine(c *gdf.ContentStream, items []lineItem, ln line) {
+	if ln.marker != nil {
+		x := tc.markerOffset(ln)
+		c.SetTextOffset(x, 0)
+		setStyle(c, tc.spans[ln.marker.span])
+		c.ShowGlyphs(ln.marker.glyphs)
+		c.SetTextOffset(-x, 0)
+	}
+	if len(items) == 0 {
+		return
+	}
+	x, order := tc.arrange(items, ln)
+	if ln.adj != 0 {
+		c.SetWordSpace(ln.adj)
+	}
+	// the line is moved by x with Td, since cm is not allowed within a text object
+	if x != 0 {
+		c.SetTextOffset(x, 0)
+	}
+
+	run := []gdf.Glyph{}
+	// inline objects are drawn outside of the text object, so the text that follows them is moved past them; pen is the
+	// distance from the start of the line to the end of the last item, and moved is the distance by which the text has been
+	// moved from the start of the line
+	var pen, moved float64
+	for _, k := range order {
+		it := items[k]
+		s := tc.spans[it.span]
+		pen += tc.itemWidth(it, ln)
+		if it.obj != nil {
+			if len(run) != 0 {
+				c.ShowGlyphs(run)
+				run = run[:0]
+			}
+			c.SetTextOffset(pen-moved, 0)
+			moved = pen
+			continue
 		}
+		if !hasStyle(c, s) {
+			if len(run) != 0 {
+				c.ShowGlyphs(run)
+				run = run[:0]
+			}
+			setStyle(c, s)
+		}
+		run = append(run, it.glyphs...)
+	}
+	if len(run) != 0 {
+		c.ShowGlyphs(run)
+	}
+
+	if c.Rise != 0 {
+		c.SetRise(0)
+	}
+	if ln.adj != 0 {
+		c.SetWordSpace(0)
+	}
+	if x+moved != 0 {
+		c.SetTextOffset(-x-moved, 0)
+	}
+}
+
+// spanColor returns the nonstroking color with which s is drawn to c, or nil if c's color is left as is.
+func spanColor(c *gdf.ContentStream, s Span) gdf.Color {
+	if s.Color == nil && c.NColor != nil {
+		return gdf.Black
+	}
+	return s.Color
+}
+
+// hasStyle reports whether c's font, font size, nonstroking color, and text rise are those with which s is drawn.
+func hasStyle(c *gdf.ContentStream, s Span) bool {
+	color := spanColor(c, s)
+	return s.Font == c.Font && s.Size == c.FontSize && (color == nil || cmpColor(c.NColor, color)) && s.Rise == c.Rise
+}
+
+// setStyle sets c's font, font size, nonstroking color, and text rise to those with which s is drawn.
+func setStyle(c *gdf.ContentStream, s Span) {
+	if s.Font != c.Font || s.Size != c.FontSize {
+		c.SetFont(s.Size, s.Font)
+	}
+	if color := spanColor(c, s); color != nil && !cmpColor(c.NColor, color) {
+		c.SetColor(color)
+	}
+	if s.Rise != c.Rise {
+		c.SetRise(s.Rise)
+	}
+}
+
+// Direction returns the base direction of the first paragraph of tc's source text, which is either LeftToRight or
+// RightToLeft. The Controller does not change the PDF's viewer preferences; if most of the text in a document is
+// right-to-left, the caller should set the Direction of the PDF's ViewPrefs to gdf.R2L.
+func (tc *Controller) Direction() Direction {
+	if len(tc.info) != 0 && tc.info[0].para%2 == 1 {
+		return RightToLeft
 	}
-	tc.n = i
-	tc.ln = lineCount
+	return LeftToRight
 }
@@ -0,0 +1,102 @@
+package text
+
+import (
+	"math"
+
+	"github.com/cdillond/gdf"
+)
+
+// A Decoration is a set of lines drawn along the text of a Span in the Span's color.
+type Decoration uint
+
+const (
+	Underline     Decoration = 1 << iota // a line below the baseline, as suggested by the font's post table
+	Strikethrough                        // a line through the text, as suggested by the font's OS/2 table
+	Overline                             // a line at the font's ascent, with the thickness of its underline
+)
+
+// A fill is a rectangle that is filled with a color.
+type fill struct {
+	gdf.Rect
+	color gdf.Color
+}
+
+// addFill appends r to fills, unless it adjoins the right side of a fill of the same color and height, which is extended
+// to cover r instead.
+func addFill(fills []fill, r gdf.Rect, color gdf.Color) []fill {
+	const epsilon = 1e-6
+	for i := len(fills) - 1; i >= 0; i-- {
+		f := &fills[i]
+		if math.Abs(f.URX-r.LLX) < epsilon && math.Abs(f.LLY-r.LLY) < epsilon && math.Abs(f.URY-r.URY) < epsilon && cmpColor(f.color, color) {
+			f.URX = r.URX
+			return fills
+		}
+	}
+	return append(fills, fill{Rect: r, color: color})
+}
+
+// drawFills fills each of the rectangles of fills with its color. It must not be called within a text object.
+func drawFills(c *gdf.ContentStream, fills []fill) {
+	for _, f := range fills {
+		if !cmpColor(c.NColor, f.color) {
+			c.SetColor(f.color)
+		}
+		c.Re2(f.Rect)
+		c.Fill(gdf.NonZero)
+	}
+}
+
+// decorate returns the highlights and decorations of tc's lines, from the next undrawn line up to line numLines, when they are
+// drawn in area with their baselines offs points below its top. Highlights cover the ascent and descent of the font of each
+// highlighted item, and decorations extend across the adjusted width of any spaces between the items they decorate.
+func (tc *Controller) decorate(area gdf.Rect, numLines int, offs []float64) (highlights, decorations []fill) {
+	tc.walk(area, numLines, offs, func(it lineItem, x, w, y float64) {
+		s := tc.spans[it.span]
+		base := y + s.Rise
+		if s.Highlight != nil {
+			asc, desc := gdf.FUToPt(float64(s.Font.Ascent), s.Size), gdf.FUToPt(float64(s.Font.Descent), s.Size)
+			highlights = addFill(highlights, gdf.Rect{LLX: x, LLY: base - desc, URX: x + w, URY: base + asc}, s.Highlight)
+		}
+		if s.Decoration != 0 {
+			color := s.Color
+			if color == nil {
+				color = gdf.Black
+			}
+			// pos is the distance from the baseline to the top of the line, in font units
+			addLine := func(pos, thickness float64) {
+				top := base + gdf.FUToPt(pos, s.Size)
+				r := gdf.Rect{LLX: x, LLY: top - gdf.FUToPt(thickness, s.Size), URX: x + w, URY: top}
+				decorations = addFill(decorations, r, color)
+			}
+			uPos, uThickness := s.Font.UnderlineMetrics()
+			if s.Decoration&Underline != 0 {
+				addLine(uPos, uThickness)
+			}
+			if s.Decoration&Strikethrough != 0 {
+				addLine(s.Font.StrikeoutMetrics())
+			}
+			if s.Decoration&Overline != 0 {
+				addLine(float64(s.Font.Ascent), uThickness)
+			}
+		}
+	})
+	return highlights, decorations
+}
+
+// walk calls f for each item of tc's lines, from the next undrawn line up to line numLines, in visual order, when the lines
+// are drawn in area with their baselines offs points below its top. f is passed the item, the x coordinate of its left
+// edge, its width, and the y coordinate of the baseline of its line, in the coordinate system of area.
+func (tc *Controller) walk(area gdf.Rect, numLines int, offs []float64, f func(it lineItem, x, w, y float64)) {
+	for k := tc.ln; k < numLines; k++ {
+		ln := tc.lines[k]
+		y := area.URY - offs[k-tc.ln]
+		items := tc.lineItems(ln)
+		x, order := tc.arrange(items, ln)
+		x += area.LLX
+		for _, i := range order {
+			w := tc.itemWidth(items[i], ln)
+			f(items[i], x, w, y)
+			x += w
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package text
+
+import (
+	"unicode"
+
+	"github.com/cdillond/gdf"
+	"github.com/go-text/typesetting/segmenter"
+)
+
+// covers reports whether f can draw the characters of the cluster t, i.e., whether it has glyphs for them and, if it is
+// not Composite, can encode them. Characters that are not drawn with glyphs of their own, such as spaces, format
+// characters, and variation selectors, are ignored.
+func covers(f *gdf.Font, t []rune) bool {
+	for _, r := range t {
+		if unicode.IsSpace(r) || unicode.In(r, unicode.Cf, unicode.Variation_Selector) {
+			continue
+		}
+		if !f.HasRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFallback divides each of spans into runs of clusters that are set in the same font: the span's own Font, if it has
+// glyphs for all of a cluster's characters, or else the first of fallback that does. Clusters that none of the fonts covers
+// are set in the span's Font. It returns the resulting spans, along with the index of the span of spans from which each
+// was taken.
+func splitFallback(spans []Span, fallback []*gdf.Font) ([]Span, []int) {
+	out := make([]Span, 0, len(spans))
+	src := make([]int, 0, len(spans))
+	var seg segmenter.Segmenter
+	for i, s := range spans {
+		if len(fallback) == 0 || s.Inline != nil {
+			out, src = append(out, s), append(src, i)
+			continue
+		}
+		text := []rune(s.Text)
+		seg.Init(text)
+		iter := seg.GraphemeIterator()
+		var start int // the start of the current run
+		font := s.Font
+		for iter.Next() {
+			g := iter.Grapheme()
+			f := s.Font
+			if !covers(f, g.Text) {
+				for _, fb := range fallback {
+					if fb != nil && covers(fb, g.Text) {
+						f = fb
+						break
+					}
+				}
+			}
+			if f != font {
+				if g.Offset > start {
+					run := s
+					run.Text, run.Font = string(text[start:g.Offset]), font
+					out, src = append(out, run), append(src, i)
+				}
+				start, font = g.Offset, f
+			}
+		}
+		run := s
+		run.Text, run.Font = string(text[start:]), font
+		out, src = append(out, run), append(src, i)
+	}
+	return out, src
+}
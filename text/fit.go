@@ -0,0 +1,157 @@
+package text
+
+import (
+	"errors"
+
+	"github.com/cdillond/gdf"
+)
+
+// ErrFit is returned by Fit and FitSpans if the text does not fit in the area at the minimum font size and horizontal scale.
+const ErrFit = ControllerErr("text does not fit in the area at the minimum font size")
+
+// A FitCfg specifies the bounds within which Fit and FitSpans adjust the size of text.
+type FitCfg struct {
+	MinSize, MaxSize float64 // the bounds of the font size, in points
+	// MinHScale is the minimum horizontal scale, as a percentage of the normal width, at which the text can be drawn. If it is
+	// 0 or at least 100, the text is not scaled horizontally.
+	MinHScale float64
+	Precision float64 // the font size is found to within Precision points; if 0, 0.1 points
+}
+
+// A Fitted is text that has been sized to fit an area. It is drawn by a Controller whose lines are Size points tall, as a
+// proportion of the ControllerCfg's FontSize, and are scaled horizontally by HScale percent.
+type Fitted struct {
+	*Controller
+	Size   float64 // the font size of the text whose size was not specified by its Span, in points
+	HScale float64 // the horizontal scale of the text, as a percentage of its normal width
+	Area   gdf.Rect
+}
+
+// Fit returns src, set in the fonts of f with the formatting specified by cfg, at the largest font size within the bounds of
+// fc at which it fits in area. The size is found by a binary search. If fc allows the text to be scaled horizontally, it is
+// set at the largest size at which it fits when scaled by fc.MinHScale, and is then scaled by the largest percentage at which
+// it fits at that size.
+func Fit(src FormatText, area gdf.Rect, f FontFamily, cfg ControllerCfg, fc FitCfg) (Fitted, error) {
+	spans, err := src.spans(f, cfg)
+	if err != nil {
+		return Fitted{}, err
+	}
+	return FitSpans(spans, area, f, cfg, fc)
+}
+
+// FitSpans is like Fit, but it fits the text of spans. The sizes and rises of the spans and of their inline objects, along
+// with cfg's Leading, are scaled in proportion to the font size.
+func FitSpans(spans []Span, area gdf.Rect, f FontFamily, cfg ControllerCfg, fc FitCfg) (Fitted, error) {
+	if cfg.FontSize <= 0 || fc.MinSize <= 0 || fc.MaxSize < fc.MinSize {
+		return Fitted{}, ErrFit
+	}
+	precision := fc.Precision
+	if precision <= 0 {
+		precision = .1
+	}
+	minScale := fc.MinHScale
+	if minScale <= 0 || minScale > 100 {
+		minScale = 100
+	}
+	// fit returns the Controller that sets the text at the given size and horizontal scale, and whether it fits in area
+	fit := func(size, scale float64) (*Controller, bool, error) {
+		k := size / cfg.FontSize
+		sc := cfg
+		sc.FontSize = size
+		sc.Leading *= k
+		ss := make([]Span, len(spans))
+		for i, s := range spans {
+			s.Size *= k
+			s.Rise *= k
+			if s.Inline != nil {
+				obj := *s.Inline
+				obj.Width, obj.Height, obj.Baseline = obj.Width*k, obj.Height*k, obj.Baseline*k
+				s.Inline = &obj
+			}
+			ss[i] = s
+		}
+		tc, err := NewSpanController(ss, area.Width()*100/scale, f, sc)
+		switch {
+		case errors.Is(err, ErrWordSize), errors.Is(err, ErrTolerance):
+			return nil, false, nil
+		case err != nil:
+			return nil, false, err
+		}
+		return &tc, tc.Height() <= area.Height()+epsilon, nil
+	}
+
+	tc, ok, err := fit(fc.MaxSize, minScale)
+	size := fc.MaxSize
+	if err == nil && !ok {
+		if tc, ok, err = fit(fc.MinSize, minScale); err != nil || !ok {
+			if err == nil {
+				err = ErrFit
+			}
+			return Fitted{}, err
+		}
+		// the text fits at size lo, but not at size hi
+		lo, hi := fc.MinSize, fc.MaxSize
+		for hi-lo > precision {
+			mid := (lo + hi) / 2
+			t, ok, err := fit(mid, minScale)
+			if err != nil {
+				return Fitted{}, err
+			}
+			if ok {
+				lo, tc = mid, t
+			} else {
+				hi = mid
+			}
+		}
+		size = lo
+	}
+	if err != nil {
+		return Fitted{}, err
+	}
+	scale := minScale
+	if scale < 100 {
+		if t, ok, err := fit(size, 100); err != nil {
+			return Fitted{}, err
+		} else if ok {
+			tc, scale = t, 100
+		} else {
+			// the text fits at scale lo, but not at scale hi
+			lo, hi := minScale, 100.0
+			for hi-lo > .5 {
+				mid := (lo + hi) / 2
+				t, ok, err := fit(size, mid)
+				if err != nil {
+					return Fitted{}, err
+				}
+				if ok {
+					lo, tc = mid, t
+				} else {
+					hi = mid
+				}
+			}
+			scale = lo
+		}
+	}
+	return Fitted{Controller: tc, Size: size, HScale: scale, Area: area}, nil
+}
+
+// Draw draws the text of ft to its Area of c. Horizontally scaled text is drawn with a transformation that has the same
+// effect as setting c's horizontal scale with SetHScale, but that also scales the positions of the lines and their
+// decorations.
+func (ft Fitted) Draw(c *gdf.ContentStream) error {
+	if ft.HScale == 100 || ft.HScale <= 0 {
+		_, _, err := ft.DrawText(c, ft.Area)
+		return err
+	}
+	k := ft.HScale / 100
+	area := ft.Area
+	area.URX = area.LLX + area.Width()/k
+	c.QSave()
+	// scale the area horizontally about its left edge
+	c.Concat(gdf.Matrix{A: k, D: 1, E: area.LLX * (1 - k)})
+	_, _, err := ft.DrawText(c, area)
+	if qerr := c.QRestore(); err == nil {
+		err = qerr
+	}
+	return err
+}
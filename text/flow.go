@@ -0,0 +1,167 @@
+package text
+
+import "github.com/cdillond/gdf"
+
+// ErrFrames is returned by Flow.Draw when a Flow runs out of frames before all of its text has been drawn.
+const ErrFrames = DrawErr("flow has no more frames")
+
+// A Frame is an area of a ContentStream into which a Flow draws text.
+type Frame struct {
+	C    *gdf.ContentStream
+	Area gdf.Rect
+}
+
+// A Block is a Controller whose text is drawn by a Flow, along with the rules that determine how its lines can be divided
+// among frames. The Orphans and Widows rules apply to each of the Controller's paragraphs.
+type Block struct {
+	*Controller
+	Orphans      int  // the minimum number of lines of a paragraph that can be left at the bottom of a frame
+	Widows       int  // the minimum number of lines of a paragraph that can be carried over to the top of a frame
+	KeepTogether bool // whether all of the Controller's lines must be drawn in the same frame
+	KeepWithNext bool // whether the Controller's last line must be drawn in the same frame as the first lines of the next Block
+}
+
+// A Flow draws the text of a sequence of Blocks to a sequence of Frames, which can belong to the same ContentStream, such as
+// the columns of a page, or to different ones, such as the bodies of successive pages. Each Block begins immediately below
+// the last line of the one before it.
+type Flow struct {
+	Frames []Frame
+	// NewFrame, if not nil, is called to obtain another Frame when the Flow has filled all of its Frames; it can, for
+	// example, add a new page to a PDF and return the page's body.
+	NewFrame func() (Frame, error)
+	frame    int     // index of the current frame
+	used     float64 // the height of the current frame that has already been drawn to
+}
+
+// Draw draws the text of blocks to fl's frames, starting where the previous call to Draw left off. The rules of each Block
+// are followed unless doing so would leave a frame empty, in which case as many lines as fit are drawn to the frame.
+// Draw returns ErrFrames if fl runs out of frames and its NewFrame function is nil, and ErrHeight if a frame is too short
+// to contain a single line.
+func (fl *Flow) Draw(blocks ...Block) error {
+	for i, b := range blocks {
+		for b.ln < len(b.lines) {
+			fr, err := fl.current()
+			if err != nil {
+				return err
+			}
+			empty := fl.used == 0
+			// the space before the block's first line is omitted at the top of a frame; it keeps the frame's used height on b's
+			// baseline grid
+			var before float64
+			if !empty {
+				before = b.snap(b.lines[b.ln].before)
+			}
+			area := fr.Area
+			area.URY -= fl.used + before
+			if err := b.check(area); err != nil {
+				return err
+			}
+			end := b.breakAt(area.Height(), empty, blocks[i+1:])
+			if end == b.ln {
+				if empty {
+					return ErrHeight
+				}
+				fl.frame, fl.used = fl.frame+1, 0
+				continue
+			}
+			// the blocks are stacked at the top of the frame, regardless of their vertical alignment
+			offs, h := b.baselines(b.ln, end)
+			if _, err := b.drawLines(fr.C, area, end, offs); err != nil {
+				return err
+			}
+			fl.used += before + h
+			if b.ln < len(b.lines) {
+				fl.frame, fl.used = fl.frame+1, 0
+			}
+		}
+	}
+	return nil
+}
+
+// current returns fl's current frame.
+func (fl *Flow) current() (Frame, error) {
+	if fl.frame < len(fl.Frames) {
+		return fl.Frames[fl.frame], nil
+	}
+	if fl.NewFrame == nil {
+		return Frame{}, ErrFrames
+	}
+	fr, err := fl.NewFrame()
+	if err != nil {
+		return Frame{}, err
+	}
+	fl.Frames = append(fl.Frames, fr)
+	return fr, nil
+}
+
+// breakAt returns the index of the line that follows the last of b's undrawn lines that can be drawn in a frame with the
+// given remaining height. empty indicates whether nothing has been drawn to the frame yet, and rest holds the Blocks that
+// follow b.
+func (b Block) breakAt(height float64, empty bool, rest []Block) int {
+	end := b.maxFit(height)
+	if end == len(b.lines) {
+		// the rest of b fits, but the next Block may need to be drawn in the same frame
+		if b.KeepWithNext && len(rest) != 0 && !empty && b.linesHeight(b.ln, end)+rest[0].leadHeight(rest[1:]) > height+epsilon {
+			return b.ln
+		}
+		return end
+	}
+	if end == b.ln || (b.KeepTogether && !empty) {
+		return b.ln
+	}
+	if k := b.keep(end); k > b.ln {
+		end = k
+	} else if !empty {
+		return b.ln
+	}
+
+	// the frame is broken within the paragraph consisting of lines first through last
+	first, last := end, end
+	for first > 0 && !b.lines[first-1].last {
+		first--
+	}
+	for !b.lines[last].last {
+		last++
+	}
+	brk := end
+	if b.Widows > 1 && last+1-brk < b.Widows {
+		brk = max(last+1-b.Widows, first)
+	}
+	// lines of a paragraph that began in an earlier frame are not orphans
+	if first >= b.ln && brk-first > 0 && brk-first < b.Orphans {
+		brk = first
+	}
+	if brk <= b.ln {
+		if empty {
+			// the rules can't be followed
+			return end
+		}
+		return b.ln
+	}
+	return brk
+}
+
+// leadHeight returns the height of the first of b's undrawn lines, which must be drawn in the same frame as the last line of
+// a preceding Block that is kept with b. rest holds the Blocks that follow b.
+func (b Block) leadHeight(rest []Block) float64 {
+	if b.ln >= len(b.lines) {
+		return 0
+	}
+	end := len(b.lines)
+	if !b.KeepTogether {
+		// the first paragraph's first line, or as many lines as are needed to avoid an orphan
+		last := b.ln
+		for !b.lines[last].last {
+			last++
+		}
+		end = min(b.ln+max(b.Orphans, 1), last+1)
+		if b.style(b.lines[b.ln]).KeepTogether {
+			end = last + 1
+		}
+	}
+	h := b.lines[b.ln].before + b.linesHeight(b.ln, end)
+	if end == len(b.lines) && b.KeepWithNext && len(rest) != 0 {
+		h += rest[0].leadHeight(rest[1:])
+	}
+	return h
+}
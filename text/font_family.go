@@ -1,6 +1,8 @@
 package text
 
 import (
+	"os"
+
 	"github.com/cdillond/gdf"
 )
 
@@ -29,3 +31,33 @@ func NewFontFamily(regular, bold, italic, boldItal string) (FontFamily, error) {
 		BoldItal: bi,
 	}, nil
 }
+
+// NewVariableFontFamily returns a FontFamily whose members are static instances of the variable font located at path.
+// The regular, bold, italic, and boldItal arguments are the names of named instances defined in the font's fvar table
+// (see gdf.NamedInstances), e.g. "Regular", "Bold", "Italic", and "Bold Italic". If an error is generated when loading
+// any of the instances, all fonts in the FontFamily returned by NewVariableFontFamily will be nil.
+func NewVariableFontFamily(path, regular, bold, italic, boldItal string) (FontFamily, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return FontFamily{}, err
+	}
+	var r, bd, i, bi *gdf.Font
+	if r, err = gdf.LoadSFNTNamedInstance(b, regular, gdf.Nonsymbolic); err != nil {
+		return FontFamily{}, err
+	}
+	if bd, err = gdf.LoadSFNTNamedInstance(b, bold, gdf.Nonsymbolic); err != nil {
+		return FontFamily{}, err
+	}
+	if i, err = gdf.LoadSFNTNamedInstance(b, italic, gdf.Nonsymbolic|gdf.Italic); err != nil {
+		return FontFamily{}, err
+	}
+	if bi, err = gdf.LoadSFNTNamedInstance(b, boldItal, gdf.Nonsymbolic|gdf.Italic); err != nil {
+		return FontFamily{}, err
+	}
+	return FontFamily{
+		Regular:  r,
+		Bold:     bd,
+		Ital:     i,
+		BoldItal: bi,
+	}, nil
+}
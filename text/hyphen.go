@@ -0,0 +1,228 @@
+package text
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Frank M. Liang's hyphenation patterns for American English, from TeX's hyphen.tex, and the accompanying list of
+// exceptions, in the plain text format distributed by the hyph-utf8 project. Their source and license are given in
+// patterns/LICENSE.
+var (
+	//go:embed patterns/hyph-en-us.pat.txt
+	enUSPatterns []byte
+	//go:embed patterns/hyph-en-us.hyp.txt
+	enUSExceptions []byte
+)
+
+// A Hyphenator finds the points at which words can be hyphenated, using Frank Liang's algorithm and a set of TeX
+// hyphenation patterns.
+type Hyphenator struct {
+	patterns   map[string][]uint8 // maps the letters of each pattern to its values
+	exceptions map[string][]int   // maps words to their hyphenation points
+	maxLen     int                // the length, in runes, of the longest pattern
+}
+
+var ErrPatterns = errors.New("hyphenation pattern file contains no patterns")
+
+// NewHyphenator returns a Hyphenator that uses the patterns read from patterns and the exceptions read from exceptions,
+// which may be nil. Both can be either TeX files, in which the patterns are enclosed by \patterns{...} and the exceptions by
+// \hyphenation{...}, or plain text files, such as the .pat.txt and .hyp.txt files of the hyph-utf8 project, that contain
+// whitespace-separated patterns or exceptions. If patterns is a TeX file that also contains a \hyphenation{...} list,
+// those exceptions are used as well. Exceptions mark each hyphenation point with a hyphen, e.g., "as-so-ciate".
+func NewHyphenator(patterns, exceptions io.Reader) (*Hyphenator, error) {
+	h := &Hyphenator{
+		patterns:   make(map[string][]uint8),
+		exceptions: make(map[string][]int),
+	}
+	b, err := io.ReadAll(patterns)
+	if err != nil {
+		return nil, err
+	}
+	pats, excs, isTeX := parseTeX(b)
+	if !isTeX {
+		pats = b
+	}
+	for _, p := range strings.Fields(string(pats)) {
+		h.addPattern(p)
+	}
+	if len(h.patterns) == 0 {
+		return nil, ErrPatterns
+	}
+	for _, e := range strings.Fields(string(excs)) {
+		h.addException(e)
+	}
+	if exceptions != nil {
+		b, err := io.ReadAll(exceptions)
+		if err != nil {
+			return nil, err
+		}
+		if _, excs, isTeX := parseTeX(b); isTeX {
+			b = excs
+		}
+		for _, e := range strings.Fields(string(b)) {
+			h.addException(e)
+		}
+	}
+	return h, nil
+}
+
+// parseTeX returns the contents of the \patterns and \hyphenation groups of the TeX file b, with comments removed, and
+// whether b contains either group.
+func parseTeX(b []byte) (patterns, exceptions []byte, ok bool) {
+	var clean []byte
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if i := bytes.IndexByte(line, '%'); i >= 0 {
+			line = line[:i]
+		}
+		clean = append(clean, line...)
+		clean = append(clean, '\n')
+	}
+	group := func(name string) []byte {
+		i := bytes.Index(clean, []byte(name+"{"))
+		if i < 0 {
+			return nil
+		}
+		ok = true
+		rest := clean[i+len(name)+1:]
+		if j := bytes.IndexByte(rest, '}'); j >= 0 {
+			rest = rest[:j]
+		}
+		return rest
+	}
+	patterns = group(`\patterns`)
+	exceptions = group(`\hyphenation`)
+	return patterns, exceptions, ok
+}
+
+// addPattern adds the pattern p, e.g., ".ach4" or "1na", to h.
+func (h *Hyphenator) addPattern(p string) {
+	var letters []rune
+	vals := []uint8{0}
+	for _, r := range p {
+		if r >= '0' && r <= '9' {
+			vals[len(vals)-1] = uint8(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		vals = append(vals, 0)
+	}
+	if len(letters) == 0 {
+		return
+	}
+	h.patterns[string(letters)] = vals
+	h.maxLen = max(h.maxLen, len(letters))
+}
+
+// addException adds the hyphenated word e, e.g., "as-so-ciate", to h.
+func (h *Hyphenator) addException(e string) {
+	var word []rune
+	var points []int
+	for _, r := range e {
+		if r == '-' {
+			points = append(points, len(word))
+			continue
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	h.exceptions[string(word)] = points
+}
+
+// Hyphenate returns the indices of the runes of word before which word can be hyphenated, in ascending order. At least
+// minLeft runes must precede, and minRight runes follow, each hyphenation point.
+func (h *Hyphenator) Hyphenate(word []rune, minLeft, minRight int) []int {
+	minLeft, minRight = max(minLeft, 1), max(minRight, 1)
+	if len(word) < minLeft+minRight {
+		return nil
+	}
+	lower := make([]rune, len(word)+2)
+	lower[0], lower[len(lower)-1] = '.', '.'
+	for i, r := range word {
+		lower[i+1] = unicode.ToLower(r)
+	}
+
+	var out []int
+	if points, ok := h.exceptions[string(lower[1:len(lower)-1])]; ok {
+		for _, p := range points {
+			if p >= minLeft && p <= len(word)-minRight {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	// vals[i] is the value of the point before lower[i]
+	vals := make([]uint8, len(lower)+1)
+	for i := range lower {
+		for j := i + 1; j <= len(lower) && j-i <= h.maxLen; j++ {
+			if pv, ok := h.patterns[string(lower[i:j])]; ok {
+				for k, v := range pv {
+					vals[i+k] = max(vals[i+k], v)
+				}
+			}
+		}
+	}
+	for i := minLeft; i <= len(word)-minRight; i++ {
+		if vals[i+1]%2 == 1 {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+var (
+	hyphenators  = map[string]*Hyphenator{}
+	hyphenMu     sync.Mutex
+	bundledOnce  sync.Once
+	bundledEnUS  *Hyphenator
+	errBundledUS error
+)
+
+// RegisterHyphenator makes h the Hyphenator used for text in the language lang, which should be a BCP 47 language tag, such
+// as "de" or "en-GB". A Controller whose Language is not registered uses the Hyphenator of the tag's base language, if one is
+// registered. Patterns for American English are bundled with this package and registered as both "en-US" and "en".
+func RegisterHyphenator(lang string, h *Hyphenator) {
+	hyphenMu.Lock()
+	defer hyphenMu.Unlock()
+	hyphenators[normTag(lang)] = h
+}
+
+// lookupHyphenator returns the Hyphenator registered for lang or its base language, or nil if there is none.
+func lookupHyphenator(lang string) *Hyphenator {
+	if lang == "" {
+		return nil
+	}
+	tag := normTag(lang)
+	base, _, _ := strings.Cut(tag, "-")
+	hyphenMu.Lock()
+	h, ok := hyphenators[tag]
+	if !ok {
+		h, ok = hyphenators[base]
+	}
+	hyphenMu.Unlock()
+	if ok {
+		return h
+	}
+	if tag == "en-us" || base == "en" {
+		bundledOnce.Do(func() {
+			bundledEnUS, errBundledUS = NewHyphenator(bytes.NewReader(enUSPatterns), bytes.NewReader(enUSExceptions))
+		})
+		if errBundledUS == nil {
+			return bundledEnUS
+		}
+	}
+	return nil
+}
+
+func normTag(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
@@ -0,0 +1,68 @@
+package text
+
+import (
+	"slices"
+
+	"github.com/cdillond/gdf"
+)
+
+// An Inline is an image or a form XObject that is set in a line of text as if it were a single, unbreakable word, e.g., an
+// icon or a small logo. It is drawn in place of the text of the Span to which it belongs, in the Span's position. The
+// line that contains it is tall enough to contain it.
+type Inline struct {
+	Image    *gdf.Image    // if not nil, the image that is drawn
+	XContent *gdf.XContent // if Image is nil, the form XObject that is drawn
+	Width    float64       // the width, in points, to which the object is scaled
+	Height   float64       // the height, in points, to which the object is scaled
+	Baseline float64       // the distance, in points, by which the object extends below the baseline of its line
+}
+
+// the rune that stands in for an inline object in the source text
+const objectReplacement = '\uFFFC'
+
+// an inline object in the token stream
+type inlineBox struct {
+	*Inline
+}
+
+func (b inlineBox) Width() float64 { return b.Inline.Width }
+
+// A placedInline is an inline object, along with the rectangle to which it is drawn.
+type placedInline struct {
+	obj *Inline
+	gdf.Rect
+}
+
+// inlines returns the inline objects of tc's lines, from the next undrawn line up to line numLines, along with the
+// rectangles to which they are drawn when the lines are drawn in area with their baselines offs points below its top.
+func (tc *Controller) inlines(area gdf.Rect, numLines int, offs []float64) []placedInline {
+	var out []placedInline
+	if !slices.ContainsFunc(tc.lines[tc.ln:numLines], func(ln line) bool { return ln.inline }) {
+		return nil
+	}
+	tc.walk(area, numLines, offs, func(it lineItem, x, w, y float64) {
+		if it.obj != nil {
+			out = append(out, placedInline{obj: it.obj, Rect: tc.objRect(it, x, y)})
+		}
+	})
+	return out
+}
+
+// objRect returns the rectangle to which the inline object of it is drawn when its left edge is at x and the baseline of
+// its line is at y.
+func (tc *Controller) objRect(it lineItem, x, y float64) gdf.Rect {
+	bottom := y + tc.spans[it.span].Rise - it.obj.Baseline
+	return gdf.Rect{LLX: x, LLY: bottom, URX: x + it.obj.Width, URY: bottom + it.obj.Height}
+}
+
+// drawInlines draws each object of objs to its rectangle of c. It must not be called within a text object.
+func drawInlines(c *gdf.ContentStream, objs []placedInline) {
+	for _, o := range objs {
+		switch {
+		case o.obj.Image != nil:
+			c.DrawImageTo(o.Rect, o.obj.Image)
+		case o.obj.XContent != nil:
+			c.DrawXContentTo(o.Rect, o.obj.XContent)
+		}
+	}
+}
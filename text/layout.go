@@ -0,0 +1,129 @@
+package text
+
+import "github.com/cdillond/gdf"
+
+// A Layout describes the positions of the lines, runs, and glyphs of some of a Controller's lines, as they would be drawn
+// to an area by DrawText, without drawing them. Distances are in points. Vertical distances are measured downward from the
+// top of the area, and horizontal distances are measured from its left edge.
+type Layout struct {
+	Lines  []LineLayout
+	Width  float64 // the width of the widest line, including its indents
+	Height float64 // the combined height of the lines
+	tc     *Controller
+	start  int // the index of the first line
+	end    int // the index of the line that follows the last line
+}
+
+// A LineLayout describes a line of a Layout.
+type LineLayout struct {
+	Top      float64 // the distance from the top of the area to the top of the line, which is above any space left empty before it
+	Height   float64 // the height of the line, including any space left empty before it
+	Baseline float64 // the distance from the top of the area to the line's baseline
+	X        float64 // the distance from the left edge of the area to the left edge of the line's text
+	Width    float64 // the width of the line's text, including any adjustment to its spaces
+	Runs     []RunLayout
+}
+
+// A RunLayout is a sequence of glyphs that are drawn next to each other on a line, in the same Span and font.
+type RunLayout struct {
+	Span   int     // the index of the Span to which the run belongs
+	Marker bool    // whether the run is a list marker
+	Inline *Inline // the inline object drawn by the run, if any; such a run has no Glyphs
+	Font   *gdf.Font
+	Size   float64
+	Color  gdf.Color
+	X      float64 // the distance from the left edge of the area to the left edge of the run
+	Width  float64
+	Glyphs []gdf.Glyph
+	// Positions are the origins of the Glyphs. The X coordinate of each is measured from the left edge of the area, and the
+	// Y coordinate is measured upward from the line's baseline; it includes the run's rise and the glyph's vertical offset.
+	Positions []gdf.Point
+}
+
+// Layout lays out the undrawn lines of tc that DrawText would draw to an area of the given height, without drawing them or
+// changing tc. The lines are positioned with tc's vertical alignment. The returned Layout can be drawn later with its Draw
+// method.
+func (tc *Controller) Layout(height float64) Layout {
+	l := Layout{tc: tc, start: tc.ln, end: tc.fit(height)}
+	offs := tc.place(l.end, height)
+	_, l.Height = tc.baselines(l.start, l.end)
+	for k := l.start; k < l.end; k++ {
+		ln := tc.lines[k]
+		base := offs[k-l.start]
+		ll := LineLayout{Top: base - ln.ascent, Height: ln.height(), Baseline: base}
+		if ln.marker != nil {
+			r := tc.runLayout(*ln.marker, ln, tc.markerOffset(ln))
+			r.Marker = true
+			ll.Runs = append(ll.Runs, r)
+		}
+		items := tc.lineItems(ln)
+		x, order := tc.arrange(items, ln)
+		ll.X = x
+		for _, i := range order {
+			it := items[i]
+			if n := len(ll.Runs); n > 0 && !ll.Runs[n-1].Marker && ll.Runs[n-1].Span == tc.srcSpans[it.span] && ll.Runs[n-1].Font == tc.spans[it.span].Font {
+				// the item continues the last run
+				r := tc.runLayout(it, ln, x)
+				ll.Runs[n-1].Glyphs = append(ll.Runs[n-1].Glyphs, r.Glyphs...)
+				ll.Runs[n-1].Positions = append(ll.Runs[n-1].Positions, r.Positions...)
+				ll.Runs[n-1].Width += r.Width
+				x += r.Width
+				continue
+			}
+			r := tc.runLayout(it, ln, x)
+			ll.Runs = append(ll.Runs, r)
+			x += r.Width
+		}
+		ll.Width = x - ll.X
+		left, right := tc.lineMargins(ln)
+		l.Width = max(l.Width, left+ln.width+right)
+		l.Lines = append(l.Lines, ll)
+	}
+	return l
+}
+
+// runLayout returns the layout of it, which is drawn on ln beginning x points from the left edge of the area.
+func (tc *Controller) runLayout(it lineItem, ln line, x float64) RunLayout {
+	s := tc.spans[it.span]
+	r := RunLayout{Span: tc.srcSpans[it.span], Font: s.Font, Size: s.Size, Color: s.Color, X: x, Glyphs: it.glyphs}
+	if it.obj != nil {
+		r.Inline, r.Width = it.obj, it.obj.Width
+		return r
+	}
+	r.Positions = make([]gdf.Point, len(it.glyphs))
+	var pen float64
+	for i, g := range it.glyphs {
+		r.Positions[i] = gdf.Point{
+			X: x + pen + gdf.FUToPt(float64(g.XOff), s.Size),
+			Y: s.Rise + gdf.FUToPt(float64(g.YOff), s.Size),
+		}
+		pen += gdf.FUToPt(float64(g.Adv), s.Size)
+		// the adjustment to the line's spaces is applied by the word spacing with which it is drawn
+		if len(g.Runes) == 1 && g.Runes[0] == '\x20' {
+			pen += ln.adj
+		}
+	}
+	r.Width = pen
+	return r
+}
+
+// Draw draws the lines of l to area of c, and returns the position of c's TextCursor after they have been drawn. The lines
+// are positioned in area with their Controller's vertical alignment, so they are drawn at the positions at which they were
+// laid out if area is as tall as the height passed to Layout; otherwise, e.g., when area is a box sized to fit l's Height,
+// they are aligned within area instead. The lines are marked as drawn, so l's Controller continues with the line that
+// follows them. A Layout can be drawn more than once; each time, its Controller is returned to the first of its lines.
+func (l Layout) Draw(c *gdf.ContentStream, area gdf.Rect) (gdf.Point, error) {
+	if l.tc == nil || l.start == l.end {
+		return *new(gdf.Point), ErrEmpty
+	}
+	if l.Height > area.Height()+epsilon {
+		return *new(gdf.Point), ErrHeight
+	}
+	ln := l.tc.ln
+	l.tc.ln = l.start
+	if err := l.tc.check(area); err != nil {
+		l.tc.ln = ln
+		return *new(gdf.Point), err
+	}
+	return l.tc.drawLines(c, area, l.end, l.tc.place(l.end, area.Height()))
+}
@@ -0,0 +1,66 @@
+package text
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/segmenter"
+	"github.com/go-text/typesetting/unicodedata"
+)
+
+// The costs of breaking a line at an opportunity that does not follow a space, expressed as multiples of the space advance.
+const (
+	freeBreak   = 0   // after a zero width space or between ideographs
+	dashBreak   = 0.5 // after a hyphen or dash, or before an em dash
+	otherBreak  = 1   // anywhere else, e.g., after a slash in a URL
+	hyphenBreak = 1   // at a hyphenation point, where a hyphen is added to the end of the line
+	noBreak     = -1
+)
+
+// isNewline reports whether r ends a paragraph.
+func isNewline(r rune) bool {
+	switch r {
+	case '\n', '\r', '\v', '\f', '\u0085', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+// breakCosts returns, for each rune of text, the cost of breaking a line immediately after it, or noBreak if the Unicode Line
+// Breaking Algorithm (UAX #14) does not allow a break there.
+func breakCosts(text []rune) []float64 {
+	out := make([]float64, len(text))
+	for i := range out {
+		out[i] = noBreak
+	}
+	var seg segmenter.Segmenter
+	seg.Init(text)
+	iter := seg.LineIterator()
+	for iter.Next() {
+		line := iter.Line()
+		end := line.Offset + len(line.Text) // the break precedes text[end]
+		if len(line.Text) == 0 {
+			continue
+		}
+		cost := float64(otherBreak)
+		before := text[end-1]
+		var after rune
+		if end < len(text) {
+			after = text[end]
+		}
+		switch {
+		case unicode.Is(unicodedata.BreakZW, before), isIdeographic(before), isIdeographic(after):
+			cost = freeBreak
+		case unicode.In(before, unicodedata.BreakHY, unicodedata.BreakBA, unicodedata.BreakB2), unicode.Is(unicodedata.BreakB2, after):
+			cost = dashBreak
+		}
+		out[end-1] = cost
+	}
+	return out
+}
+
+// isIdeographic reports whether r belongs to one of the line breaking classes of the ideographic scripts, between whose
+// characters lines can be broken freely.
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicodedata.BreakID, unicodedata.BreakCJ, unicodedata.BreakH2, unicodedata.BreakH3,
+		unicodedata.BreakJL, unicodedata.BreakJV, unicodedata.BreakJT)
+}
@@ -0,0 +1,63 @@
+package text
+
+import (
+	"math"
+
+	"github.com/cdillond/gdf"
+)
+
+// A linkAreas is the area covered by the text of a linked span when it is drawn, which is broken into a rectangle for each
+// line, or for each visual run of the span within a line.
+type linkAreas struct {
+	span  int
+	areas []gdf.Rect
+}
+
+// links returns the areas covered by the text of each of tc's linked spans in its lines, from the next undrawn line up to
+// line numLines, when they are drawn in area with their baselines offs points below its top. Each area covers the ascent
+// and descent of the span's font, or the rectangle of its inline object.
+func (tc *Controller) links(area gdf.Rect, numLines int, offs []float64) []linkAreas {
+	if !tc.linked {
+		return nil
+	}
+	const epsilon = 1e-6
+	var out []linkAreas
+	tc.walk(area, numLines, offs, func(it lineItem, x, w, y float64) {
+		s := tc.spans[it.span]
+		if s.Dest == nil && s.URI == "" {
+			return
+		}
+		var r gdf.Rect
+		if it.obj != nil {
+			r = tc.objRect(it, x, y)
+		} else {
+			base := y + s.Rise
+			r = gdf.Rect{LLX: x, LLY: base - gdf.FUToPt(float64(s.Font.Descent), s.Size), URX: x + w, URY: base + gdf.FUToPt(float64(s.Font.Ascent), s.Size)}
+		}
+		k := len(out) - 1
+		for k >= 0 && tc.srcSpans[out[k].span] != tc.srcSpans[it.span] {
+			k--
+		}
+		if k < 0 {
+			out = append(out, linkAreas{span: it.span, areas: []gdf.Rect{r}})
+			return
+		}
+		// the item extends the last area of the span if it adjoins its right side on the same line
+		last := &out[k].areas[len(out[k].areas)-1]
+		if math.Abs(last.URX-r.LLX) < epsilon && math.Abs(last.LLY-r.LLY) < epsilon {
+			last.URX = r.URX
+			last.URY = max(last.URY, r.URY)
+			return
+		}
+		out[k].areas = append(out[k].areas, r)
+	})
+	return out
+}
+
+// addLinks adds a link annotation for each of links to the page to which c belongs.
+func (tc *Controller) addLinks(c *gdf.ContentStream, links []linkAreas) {
+	for _, l := range links {
+		s := tc.spans[l.span]
+		c.LinkAreas(&gdf.LinkAnnot{Dest: s.Dest, URI: s.URI}, l.areas...)
+	}
+}
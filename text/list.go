@@ -0,0 +1,149 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ListStyle uint
+
+const (
+	Bullet     ListStyle = iota // each item is marked by the List's Bullet
+	Decimal                     // 1, 2, 3, ...
+	LowerAlpha                  // a, b, c, ..., z, aa, ab, ...
+	UpperAlpha                  // A, B, C, ..., Z, AA, AB, ...
+	LowerRoman                  // i, ii, iii, iv, ...
+	UpperRoman                  // I, II, III, IV, ...
+)
+
+// A List numbers or bullets the paragraphs of a Controller whose ControllerCfg refers to it. Each paragraph is an item of the
+// list, and its marker is drawn in the margin before the item's first line, so that the item's text hangs from the marker.
+// The items of each Controller are numbered from the List's Start, so a Controller can be laid out any number of times. To
+// continue a list in a later Controller, e.g., after the items of a nested list, give the later Controller a copy of the List
+// whose Start is the value returned by the earlier Controller's NextListItem method. Since gdf does not write tagged PDF, a
+// List's items are only drawn as text; they are not tagged as L and LI elements, so assistive technology cannot identify them.
+type List struct {
+	Style  ListStyle
+	Bullet rune // the marker of a Bullet list; if 0, '•'
+	// Format is the fmt format (e.g., "%s.", "%s)", or "(%s)") with which the number of each item of a numbered list is
+	// written; if empty, "%s.".
+	Format string
+	Start  int // the number of the first item; if 0, 1
+	// Level is the nesting level of the list; it is 0 for a top-level list, 1 for a list nested within one of its items, and so on.
+	// The text of each item is indented by (Level+1)*Indent from the starting margin, and its marker ends one space before the text.
+	Level  int
+	Indent float64 // the indent, in points, of each nesting level; if 0, 18 points
+}
+
+// the indent of each level of a List, if it is not specified
+const defaultListIndent = 18
+
+// indent returns the distance, in points, between the starting margin and the text of l's items.
+func (l *List) indent() float64 {
+	if l == nil {
+		return 0
+	}
+	in := l.Indent
+	if in == 0 {
+		in = defaultListIndent
+	}
+	return float64(l.Level+1) * in
+}
+
+// start returns the number of l's first item.
+func (l *List) start() int {
+	if l.Start == 0 {
+		return 1
+	}
+	return l.Start
+}
+
+// item returns the marker of l's ith item, counting from 0.
+func (l *List) item(i int) string {
+	n := l.start() + i
+	if l.Style == Bullet {
+		if l.Bullet == 0 {
+			return "•"
+		}
+		return string(l.Bullet)
+	}
+	format := l.Format
+	if format == "" {
+		format = "%s."
+	}
+	var num string
+	switch l.Style {
+	case Decimal:
+		num = strconv.Itoa(n)
+	case LowerAlpha:
+		num = alpha(n)
+	case UpperAlpha:
+		num = strings.ToUpper(alpha(n))
+	case LowerRoman:
+		num = roman(n)
+	case UpperRoman:
+		num = strings.ToUpper(roman(n))
+	}
+	return fmt.Sprintf(format, num)
+}
+
+// alpha returns n in bijective base 26, using the letters a through z as digits. If n is less than 1, it is written in decimal.
+func alpha(n int) string {
+	if n < 1 {
+		return strconv.Itoa(n)
+	}
+	var out []rune
+	for ; n > 0; n = (n - 1) / 26 {
+		out = append(out, 'a'+rune((n-1)%26))
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// roman returns n as a lowercase roman numeral. If n is not in [1,3999], it is written in decimal.
+func roman(n int) string {
+	if n < 1 || n > 3999 {
+		return strconv.Itoa(n)
+	}
+	numerals := []struct {
+		val int
+		s   string
+	}{
+		{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+		{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+	}
+	var b strings.Builder
+	for _, num := range numerals {
+		for ; n >= num.val; n -= num.val {
+			b.WriteString(num.s)
+		}
+	}
+	return b.String()
+}
+
+// marker returns the shaped marker of the list item that begins at src[i], which belongs to the paragraph with the embedding
+// level para, or nil if tc is not a list or the paragraph is empty.
+func (tc *Controller) marker(src []rune, i int, para uint8, span int) (*lineItem, error) {
+	if tc.list == nil || isNewline(src[i]) {
+		return nil, nil
+	}
+	s := tc.spans[span]
+	glyphs, err := s.Font.ShapeDirection([]rune(tc.list.item(tc.items)), para%2 == 1, tc.features)
+	if err != nil {
+		return nil, err
+	}
+	tc.items++
+	return &lineItem{glyphs: glyphs, span: span, level: para}, nil
+}
+
+// NextListItem returns the number of the item that follows the last of tc's list items, i.e., the Start of a List that
+// continues tc's list. If tc is not a list, it returns 0.
+func (tc *Controller) NextListItem() int {
+	if tc.list == nil {
+		return 0
+	}
+	return tc.list.start() + tc.items
+}
@@ -0,0 +1,75 @@
+package text
+
+import "math"
+
+// A ParaStyle specifies the formatting of a paragraph. A paragraph takes the ParaStyle of the Span in which it begins; if
+// that Span's Para is nil, the paragraph is formatted as specified by the Controller's ControllerCfg.
+type ParaStyle struct {
+	Alignment
+	Justification
+	FirstIndent float64 // the indent, in points, of the paragraph's first line; if it is negative, the line hangs
+	LeftIndent  float64 // the indent, in points, of each line from the left edge of the area in which it is drawn
+	RightIndent float64 // the indent, in points, of each line from the right edge of the area in which it is drawn
+	// SpaceBefore is the space, in points, above the paragraph's first line. It is omitted when the line is drawn at the top
+	// of a frame or of an area to which DrawText draws the text.
+	SpaceBefore float64
+	SpaceAfter  float64 // the space, in points, between the paragraph's last line and the first line of the next paragraph
+	// KeepTogether indicates whether all of the paragraph's lines must be drawn to the same area. KeepWithNext indicates
+	// whether the paragraph's last line must be drawn to the same area as the first line of the next paragraph of the text.
+	// These rules are followed unless doing so would leave an area empty.
+	KeepTogether, KeepWithNext bool
+}
+
+// paraStyle returns the style of the paragraph that begins in tc.spans[span].
+func (tc *Controller) paraStyle(span int) ParaStyle {
+	if p := tc.spans[span].Para; p != nil {
+		return *p
+	}
+	return tc.defStyle
+}
+
+// style returns the style of the paragraph to which ln belongs.
+func (tc *Controller) style(ln line) ParaStyle { return tc.styles[ln.paragraph] }
+
+// indents returns the combined width, in points, of the left and right indents of the lines of paragraph p, including the
+// indent of tc's list.
+func (tc *Controller) indents(p int) float64 {
+	s := tc.styles[p]
+	return s.LeftIndent + s.RightIndent + tc.list.indent()
+}
+
+// the maximum number of times that tc's lines are broken again with relaxed tolerances
+const maxRetries = 16
+
+// tolerances returns the ratios of the minimum and maximum allowable space advances and the normal space advance in the
+// lines of paragraph p, when tc's lines are broken for the nth time. Each attempt relaxes the tolerances of the last.
+func (tc *Controller) tolerances(p, n int) (squish, stretch float64) {
+	squish, stretch = tc.tightness, tc.looseness
+	if tc.styles[p].Justification == Ragged {
+		squish, stretch = 0, 1
+	}
+	return squish * math.Pow(1.25, float64(n)), stretch * math.Pow(2, float64(n))
+}
+
+// keep returns the index of the line that follows the last of tc's undrawn lines, up to line end, that can be drawn to an
+// area without separating lines that the styles of their paragraphs keep together. It returns tc.ln if the rules cannot be
+// followed.
+func (tc *Controller) keep(end int) int {
+	brk := end
+	for brk > tc.ln && brk < len(tc.lines) {
+		// the first line of the paragraph to which line brk belongs
+		first := brk
+		for first > 0 && !tc.lines[first-1].last {
+			first--
+		}
+		switch {
+		case first < brk && tc.style(tc.lines[brk]).KeepTogether:
+			brk = first
+		case first == brk && tc.style(tc.lines[brk-1]).KeepWithNext:
+			brk--
+		default:
+			return brk
+		}
+	}
+	return brk
+}
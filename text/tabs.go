@@ -0,0 +1,159 @@
+package text
+
+import (
+	"math"
+	"slices"
+
+	"github.com/cdillond/gdf"
+)
+
+type TabAlignment uint
+
+const (
+	TabLeft    TabAlignment = iota // the text after the tab starts at the stop
+	TabRight                       // the text after the tab ends at the stop
+	TabCenter                      // the text after the tab is centered on the stop
+	TabDecimal                     // the decimal separator of the text after the tab is aligned with the stop
+)
+
+// A TabStop is a position on a line to which a horizontal tab ('\t') advances the text that follows it. The text after a
+// tab extends to the next tab in the line, or to the end of the line.
+type TabStop struct {
+	Pos     float64 // the distance, in points, of the stop from the line's starting margin
+	Align   TabAlignment
+	Leader  rune // if not 0, the character that is repeated to fill the space before the stop, e.g., '.'
+	Decimal rune // the decimal separator used by a TabDecimal stop; if 0, '.'
+}
+
+// the distance between default tab stops, if it is not specified by the ControllerCfg
+const defaultTabInterval = 36
+
+// a horizontal tab; its width depends on its position in the line, so it is only known once the line has been broken
+type tab struct {
+	width  float64
+	leader rune
+}
+
+func (t tab) Width() float64 { return t.width }
+
+// expandTabs returns the width of a line consisting of tc.tokens[start:end] and broken at tc.tokens[end], with the given
+// indent, after the line's tabs have been expanded. If set is true, the tab tokens are updated with their expanded widths
+// and leaders.
+func (tc *Controller) expandTabs(start, end int, indent float64, set bool) float64 {
+	x := indent
+	for i := start; i < end; i++ {
+		switch v := tc.tokens[i].(type) {
+		case tab:
+			w, leader := tc.tabWidth(x, i, end)
+			if set {
+				tc.tokens[i] = tab{width: w, leader: leader}
+			}
+			x += w
+		case box, inlineBox, skip, boundSkip:
+			x += v.Width()
+		}
+	}
+	if h, ok := tc.tokens[end].(hyphen); ok {
+		x += h.Width()
+	}
+	return x
+}
+
+// tabWidth returns the width of the tab tc.tokens[i], which begins x points from the start of a line that is broken at
+// tc.tokens[end], along with the leader with which it is filled.
+func (tc *Controller) tabWidth(x float64, i, end int) (float64, rune) {
+	// the text that follows the tab, up to the next tab or the end of the line
+	var seg float64
+	j := i + 1
+	for ; j < end; j++ {
+		if _, ok := tc.tokens[j].(tab); ok {
+			break
+		}
+		switch tc.tokens[j].(type) {
+		case box, inlineBox, skip, boundSkip:
+			seg += tc.tokens[j].Width()
+		}
+	}
+	if j == end {
+		if h, ok := tc.tokens[end].(hyphen); ok {
+			seg += h.Width()
+		}
+	}
+	for _, st := range tc.tabStops {
+		if st.Pos <= x+epsilon {
+			continue
+		}
+		var w float64
+		switch st.Align {
+		case TabLeft:
+			w = st.Pos - x
+		case TabRight:
+			w = st.Pos - seg - x
+		case TabCenter:
+			w = st.Pos - seg/2 - x
+		case TabDecimal:
+			w = st.Pos - tc.decimalOffset(i+1, j, st.Decimal) - x
+		}
+		// if the text does not fit before the stop, the next stop is used
+		if w >= 0 {
+			return w, st.Leader
+		}
+	}
+	interval := tc.tabInterval
+	if interval <= 0 {
+		interval = defaultTabInterval
+	}
+	return (math.Floor((x+epsilon)/interval)+1)*interval - x, 0
+}
+
+// decimalOffset returns the width of the text of tc.tokens[start:end] that precedes the first occurrence of the decimal
+// separator dec, or of the entire text if it does not contain dec.
+func (tc *Controller) decimalOffset(start, end int, dec rune) float64 {
+	if dec == 0 {
+		dec = '.'
+	}
+	var w float64
+	for i := start; i < end; i++ {
+		switch v := tc.tokens[i].(type) {
+		case box:
+			if k := slices.Index(v.chars, dec); k >= 0 && len(v.glyphs) != 0 {
+				// the glyphs of the box's clusters that precede the separator
+				first := v.glyphs[0].Cluster
+				var adv int
+				for _, g := range v.glyphs {
+					if g.Cluster-first < k {
+						adv += g.Adv
+					}
+				}
+				return w + gdf.FUToPt(float64(adv), tc.spans[tc.info[i].span].Size)
+			}
+			w += v.Width()
+		case inlineBox, skip, boundSkip:
+			w += v.Width()
+		}
+	}
+	return w
+}
+
+// tabItem returns the item that draws the expanded tab t, which belongs to the span s. The tab is drawn as a space, or, if
+// it has a leader, as a sequence of leaders that ends at the tab stop.
+func tabItem(t tab, s Span, span int, level uint8) lineItem {
+	width := int(math.Round(gdf.PtToFU(t.width, s.Size)))
+	sp := s.Font.RuneGlyph(' ')
+	sp.Adv = width
+	it := lineItem{glyphs: []gdf.Glyph{sp}, span: span, level: level}
+	if t.leader == 0 {
+		return it
+	}
+	l := s.Font.RuneGlyph(t.leader)
+	adv := s.Font.GlyphAdvance(t.leader)
+	if adv <= 0 || width < adv {
+		return it
+	}
+	n := width / adv
+	it.glyphs[0].Adv = width - n*adv
+	for range n {
+		it.glyphs = append(it.glyphs, l)
+	}
+	return it
+}
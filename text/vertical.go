@@ -0,0 +1,87 @@
+package text
+
+import "math"
+
+type VAlignment uint
+
+const (
+	Top        VAlignment = iota // the lines are drawn at the top of the area
+	Middle                       // the lines are centered vertically in the area
+	Bottom                       // the lines are drawn at the bottom of the area
+	VJustified                   // the lines fill the area; the extra space is divided equally among the gaps between paragraphs
+)
+
+// snap returns the distance of the first line of tc's baseline grid that is at least x points below the top of an area.
+// If tc has no baseline grid, it returns x.
+func (tc *Controller) snap(x float64) float64 {
+	if tc.grid <= 0 {
+		return x
+	}
+	return math.Ceil(x/tc.grid-epsilon) * tc.grid
+}
+
+// snapDown returns the greatest multiple of tc's baseline grid spacing that is no greater than x. If tc has no baseline grid,
+// it returns x.
+func (tc *Controller) snapDown(x float64) float64 {
+	if tc.grid <= 0 {
+		return x
+	}
+	return math.Floor(x/tc.grid+epsilon) * tc.grid
+}
+
+// baselines returns the distances from the top of an area to the baselines of tc's lines from line i up to line j, when line
+// i is drawn at the top of the area, along with the height of the lines. The space before line i is omitted. If tc has a
+// baseline grid, each baseline is moved down to the next line of the grid, and the height is a multiple of the grid spacing.
+func (tc *Controller) baselines(i, j int) ([]float64, float64) {
+	offs := make([]float64, 0, j-i)
+	var bottom float64
+	for k := i; k < j; k++ {
+		ln := tc.lines[k]
+		if k > i {
+			bottom += ln.before
+		}
+		base := tc.snap(bottom + ln.ascent)
+		offs = append(offs, base)
+		bottom = base + ln.descent
+	}
+	return offs, tc.snap(bottom)
+}
+
+// place returns the distances from the top of an area of the given height to the baselines of tc's undrawn lines up to line
+// numLines, when they are drawn to the area with tc's vertical alignment.
+func (tc *Controller) place(numLines int, height float64) []float64 {
+	offs, h := tc.baselines(tc.ln, numLines)
+	extra := height - h
+	if extra <= epsilon || len(offs) == 0 {
+		return offs
+	}
+	var shift float64
+	switch tc.vAlign {
+	case Middle:
+		shift = tc.snapDown(extra / 2)
+	case Bottom:
+		shift = tc.snapDown(extra)
+	case VJustified:
+		var gaps int
+		for k := tc.ln + 1; k < numLines; k++ {
+			if tc.lines[k-1].last {
+				gaps++
+			}
+		}
+		if gaps == 0 {
+			return offs
+		}
+		gap := tc.snapDown(extra / float64(gaps))
+		for k := range offs {
+			if k > 0 && tc.lines[tc.ln+k-1].last {
+				shift += gap
+			}
+			offs[k] += shift
+		}
+		return offs
+	}
+	for k := range offs {
+		offs[k] += shift
+	}
+	return offs
+}
@@ -28,9 +28,9 @@ func (c *ContentStream) SetTextMatrix(m Matrix) {
 	c.buf = cmdf(c.buf, op_Tm, m.A, m.B, m.C, m.D, m.E, m.F)
 }
 
-// SetTextOffset offsets the current text object's text matrix by x and y, and sets the text object's line matrix equal to its text matrix.
+// SetTextOffset offsets the current text object's line matrix by x and y, and sets the text object's text matrix equal to its line matrix; Td.
 func (c *ContentStream) SetTextOffset(x, y float64) {
-	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, x, y})
+	c.TextObj.Matrix = Mul(c.LineMatrix, Matrix{1, 0, 0, 1, x, y})
 	c.LineMatrix = c.TextObj.Matrix
 	c.buf = cmdf(c.buf, op_Td, x, y)
 }
@@ -38,7 +38,7 @@ func (c *ContentStream) SetTextOffset(x, y float64) {
 // SetTextOffsetLeading sets the content stream's current leading to y and then calls c.TextOffset(x, y).
 func (c *ContentStream) SetTextOffsetLeading(x, y float64) {
 	c.SetLeading(-y)
-	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, x, y})
+	c.TextObj.Matrix = Mul(c.LineMatrix, Matrix{1, 0, 0, 1, x, y})
 	c.LineMatrix = c.TextObj.Matrix
 	c.buf = cmdf(c.buf, op_Td, x, y)
 }
@@ -52,6 +52,10 @@ func (c *ContentStream) NextLine() {
 
 // ShowString writes s (without kerning) to c and advances the text matrix by the extent of s; Tj.
 func (c *ContentStream) ShowString(s string) {
+	if c.Font.Composite {
+		c.ShowGlyphs(c.Font.runeGlyphs([]rune(s), nil))
+		return
+	}
 	ext := FUToPt(c.RawExtent([]rune(s)), c.FontSize)
 	b, _ := c.Font.enc.Bytes([]byte(s))
 	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, ext, 0})
@@ -63,6 +67,11 @@ func (c *ContentStream) ShowString(s string) {
 
 // LineString writes s (without kerning) to c and advances the text matrix by the extent of s; '.
 func (c *ContentStream) LineString(s string) {
+	if c.Font.Composite {
+		c.NextLine()
+		c.ShowGlyphs(c.Font.runeGlyphs([]rune(s), nil))
+		return
+	}
 	ext := FUToPt(c.RawExtent([]rune(s)), c.FontSize)
 	b, _ := c.Font.enc.Bytes([]byte(s))
 	c.TextObj.Matrix = Mul(c.TextObj.Matrix, Matrix{1, 0, 0, 1, ext, -c.Leading})
@@ -77,6 +86,10 @@ func (c *ContentStream) ShowText(t []rune, kerns []int) error {
 	if len(t) != len(kerns) {
 		return fmt.Errorf("equal number of runes and kerns required. rune count: %d, kern count: %d", len(t), len(kerns))
 	}
+	if c.Font.Composite {
+		c.ShowGlyphs(c.Font.runeGlyphs(t, kerns))
+		return nil
+	}
 	c.buf = append(c.buf, '[')
 
 	tmp := make([]byte, 0, 512)
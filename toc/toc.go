@@ -0,0 +1,207 @@
+// Package toc generates tables of contents. Headings are registered with a TOC while a document's content is generated,
+// and once the content is complete, the TOC sets its entries with text.Controllers, with dot leaders between their titles
+// and right-aligned page numbers, and inserts the resulting pages into the PDF. The entries can also be made into links to
+// their headings and added to the PDF's document outline.
+package toc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cdillond/gdf"
+	"github.com/cdillond/gdf/text"
+)
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	ErrDest     = Error("entry's destination page is not in the PDF")
+	ErrTooLarge = Error("entry does not fit on an empty page")
+)
+
+// the indent of each level of entries, if it is not specified
+const defaultIndent = 18
+
+// An Entry is a heading listed in a table of contents.
+type Entry struct {
+	Title string
+	Level int      // the nesting level of the heading; 0 for top-level headings, 1 for their subheadings, and so on
+	Dest  gdf.Dest // the location of the heading
+}
+
+// A TOC is a table of contents. Its Entries are listed in the order in which they were added.
+type TOC struct {
+	Entries []Entry
+	Title   string // if not empty, the title drawn in bold above the entries, at 1.5 times the size of their text
+	Family  text.FontFamily
+	// Cfg specifies the formatting of the entries. The TabStops of each entry are replaced by a right-aligned stop at the
+	// end of its lines, and its LeftIndent is increased by Indent for each level.
+	Cfg      text.ControllerCfg
+	Indent   float64 // the indent, in points, of each level of entries; if 0, 18 points
+	Leader   rune    // the character that fills the space between each title and its page number; if 0, '.'
+	PageSize gdf.Rect
+	Margins  gdf.Margins
+	// PageLabel returns the label of the page whose 0-based index in the PDF, after the TOC's pages are inserted, is i. If
+	// PageLabel is nil, pages are numbered from 1.
+	PageLabel func(i int) string
+	Links     bool // whether the entries are linked to their destinations
+	Outline   bool // whether the entries are added to the PDF's document outline
+}
+
+// New returns a TOC whose entries are set in the fonts of f with the formatting specified by cfg, on pages of the given
+// size and margins.
+func New(f text.FontFamily, cfg text.ControllerCfg, pageSize gdf.Rect, margins gdf.Margins) *TOC {
+	return &TOC{Family: f, Cfg: cfg, PageSize: pageSize, Margins: margins}
+}
+
+// Add adds an entry for the heading with the given title, nesting level, and destination to t.
+func (t *TOC) Add(title string, level int, dest gdf.Dest) {
+	t.Entries = append(t.Entries, Entry{Title: title, Level: level, Dest: dest})
+}
+
+// a placed is a Controller that is drawn at the top of an area of a TOC page
+type placed struct {
+	tc    *text.Controller
+	entry int // the index of the entry set by tc, or -1 if tc sets the TOC's title
+	area  gdf.Rect
+	link  gdf.Rect // the area of the entry's link
+}
+
+// Insert sets t's entries on new pages, which it inserts into pdf at index i, and returns the number of pages inserted.
+// The entries' page numbers are those of their destinations after the pages are inserted.
+func (t *TOC) Insert(pdf *gdf.PDF, i int) (int, error) {
+	// the entries' page numbers depend on the number of TOC pages, which in turn depends on the widths of the page
+	// numbers; since that number never decreases as the page numbers grow, this converges
+	n := 1
+	var pages [][]placed
+	for range len(t.Entries) + 2 {
+		var err error
+		if pages, err = t.layout(pdf, i, n); err != nil {
+			return 0, err
+		}
+		if len(pages) == n {
+			break
+		}
+		n = len(pages)
+	}
+	for k, pl := range pages {
+		p := gdf.NewPage(t.PageSize, t.Margins)
+		c := p.ContentStream()
+		for _, x := range pl {
+			if _, _, err := x.tc.DrawText(c, x.area); err != nil {
+				return 0, err
+			}
+			if t.Links && x.entry >= 0 {
+				dest := t.Entries[x.entry].Dest
+				c.Link(&gdf.LinkAnnot{Dest: &dest}, x.link)
+			}
+		}
+		if err := pdf.InsertPage(&p, i+k); err != nil {
+			return 0, err
+		}
+	}
+	if t.Outline {
+		t.addOutline(pdf)
+	}
+	return len(pages), nil
+}
+
+// layout sets t's entries, as though n TOC pages were inserted into pdf at index at, and assigns them to pages.
+func (t *TOC) layout(pdf *gdf.PDF, at, n int) ([][]placed, error) {
+	area := t.PageSize.Bounds(t.Margins)
+	var pages [][]placed
+	var used float64
+	add := func(tc *text.Controller, entry int, indent float64) error {
+		h := tc.Height()
+		if h > area.Height() {
+			return fmt.Errorf("%w: %d", ErrTooLarge, entry)
+		}
+		if len(pages) == 0 || used+h > area.Height() {
+			pages = append(pages, nil)
+			used = 0
+		}
+		r := area
+		r.URY -= used
+		r.LLY = r.URY - h
+		link := r
+		link.LLX += indent
+		pages[len(pages)-1] = append(pages[len(pages)-1], placed{tc: tc, entry: entry, area: r, link: link})
+		used += h
+		return nil
+	}
+	if t.Title != "" {
+		cfg := t.Cfg
+		cfg.IsBold = true
+		cfg.FontSize *= 1.5
+		cfg.Leading *= 1.5
+		cfg.TabStops, cfg.List = nil, nil
+		tc, err := text.NewSpanController([]text.Span{{Text: t.Title}}, area.Width(), t.Family, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = add(&tc, -1, 0); err != nil {
+			return nil, err
+		}
+		// the title is separated from the entries by a blank line
+		used += t.Cfg.Leading
+	}
+	indent := t.Indent
+	if indent == 0 {
+		indent = defaultIndent
+	}
+	leader := t.Leader
+	if leader == 0 {
+		leader = '.'
+	}
+	for k, e := range t.Entries {
+		j := pdf.PageIndex(e.Dest.Page)
+		if j < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrDest, e.Title)
+		}
+		if j >= at {
+			j += n
+		}
+		label := strconv.Itoa(j + 1)
+		if t.PageLabel != nil {
+			label = t.PageLabel(j)
+		}
+		cfg := t.Cfg
+		cfg.List = nil
+		cfg.LeftIndent += float64(max(e.Level, 0)) * indent
+		cfg.TabStops = []text.TabStop{{Pos: area.Width() - cfg.LeftIndent - cfg.RightIndent, Align: text.TabRight, Leader: leader}}
+		title := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(e.Title)
+		tc, err := text.NewSpanController([]text.Span{{Text: title + "\t" + label}}, area.Width(), t.Family, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = add(&tc, k, cfg.LeftIndent); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// addOutline adds t's entries to the document outline of pdf. Each entry is a child of the last entry before it of a
+// lower level.
+func (t *TOC) addOutline(pdf *gdf.PDF) {
+	var top []*gdf.OutlineItem
+	var stack []*gdf.OutlineItem
+	var levels []int
+	for _, e := range t.Entries {
+		item := &gdf.OutlineItem{Title: e.Title, Dest: e.Dest}
+		for len(stack) > 0 && levels[len(levels)-1] >= e.Level {
+			stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+		}
+		if len(stack) == 0 {
+			top = append(top, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		stack, levels = append(stack, item), append(levels, e.Level)
+	}
+	pdf.AddOutlineItems(top...)
+}